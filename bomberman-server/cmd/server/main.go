@@ -3,23 +3,39 @@ package main
 import (
     "log"
     "net/http"
+    "os"
 
+    "bomberman-server/internal/config"
     "bomberman-server/internal/server"
 )
 
 func main() {
+    // Load configuration (file, then BOMBERMAN_* env vars, then flags)
+    cfg, err := config.Load(os.Args[1:])
+    if err != nil {
+        log.Fatalf("Could not load configuration: %s\n", err)
+    }
+
     // Initialize the server
-    srv := server.NewServer()
+    srv, err := server.NewServer(cfg)
+    if err != nil {
+        log.Fatalf("Could not create server: %s\n", err)
+    }
 
     // Set up routes
     srv.SetupRoutes()
 
-    // Start the WebSocket hub
-    go srv.StartWebSocketHub()
+    httpServer := &http.Server{
+        Addr:         cfg.Addr(),
+        Handler:      srv.Router,
+        ReadTimeout:  cfg.Server.ReadTimeout,
+        WriteTimeout: cfg.Server.WriteTimeout,
+        IdleTimeout:  cfg.Server.IdleTimeout,
+    }
 
     // Start the HTTP server
-    log.Println("Starting server on :8080")
-    if err := http.ListenAndServe(":8080", srv.Router); err != nil {
+    log.Printf("Starting server on %s\n", cfg.Addr())
+    if err := httpServer.ListenAndServe(); err != nil {
         log.Fatalf("Could not start server: %s\n", err)
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,310 @@
+package bot
+
+import (
+	"math/rand"
+	"time"
+
+	"bomberman-server/internal/game"
+)
+
+// Level is how well a bot plays
+type Level struct {
+	Think      time.Duration // Time between two decisions
+	Margin     time.Duration // Slack kept when running past a bomb about to go off
+	Aggression float64       // 0-1: how keen the bot is to bomb and hunt opponents
+	Mistakes   float64       // Chance per decision of a random step, whatever the danger
+	Chains     bool          // Whether the bot expects bombs to set each other off
+}
+
+// Levels holds the behaviour of each game.BotDifficulties difficulty
+var Levels = map[string]Level{
+	game.BotEasy:   {Think: 300 * time.Millisecond, Aggression: 0.2, Mistakes: 0.05},
+	game.BotNormal: {Think: 150 * time.Millisecond, Margin: 100 * time.Millisecond, Aggression: 0.5, Mistakes: 0.01, Chains: true},
+	game.BotHard:   {Think: 80 * time.Millisecond, Margin: 150 * time.Millisecond, Aggression: 0.9, Chains: true},
+}
+
+// Values of the goals a bot walks to, less one per step of the way
+const (
+	powerUpValue = 10.0
+	enemyValue   = 14.0 // Scaled by Aggression
+	blockValue   = 4.0
+	huntValue    = 8.0 // Scaled by Aggression, less the distance left to the nearest enemy
+)
+
+// brain decides the actions of one bot from one view of the world
+type brain struct {
+	w     *world
+	self  *game.Player
+	level Level
+	rng   *rand.Rand
+
+	stepTime time.Duration // Time the bot takes per tile, at best one step a decision
+	margin   time.Duration
+}
+
+// think returns the actions the bot takes now, in order. A bot cursed with reverse
+// has its steps turned around, so that it still ends up where it meant to go.
+func think(w *world, self *game.Player, level Level, rng *rand.Rand) []string {
+	actions := decide(w, self, level, rng)
+	if self.Curse == game.CurseReverse {
+		for i, action := range actions {
+			actions[i] = reverse(action)
+		}
+	}
+	return actions
+}
+
+// decide picks the actions of the bot as if its moves went where they say
+func decide(w *world, self *game.Player, level Level, rng *rand.Rand) []string {
+	b := &brain{w: w, self: self, level: level, rng: rng}
+	b.stepTime = self.MoveCooldown(w.moveInterval)
+	if b.stepTime < level.Think {
+		b.stepTime = level.Think
+	}
+	b.margin = level.Think + level.Margin
+
+	if rng.Float64() < level.Mistakes {
+		return b.wander()
+	}
+
+	// With diarrhea the game drops a bomb under the bot whenever it can, so the bot
+	// plans as if one were already there
+	var dropped *game.Bomb
+	diarrhea := self.Curse == game.CurseDiarrhea
+	if diarrhea {
+		dropped = b.nextBomb()
+	}
+	danger := w.danger(self.ID, level.Chains, dropped)
+	if danger.at(self.Position) != safe {
+		return b.flee(danger)
+	}
+	if diarrhea {
+		// No bombs of its own making: they would only pile up on the dropped ones
+		if move, ok := b.seek(danger); ok {
+			return []string{move}
+		}
+		return nil
+	}
+
+	// Detonating is the only action of the decision: the blast goes off at the end of
+	// the tick, after any step taken with it
+	if b.shouldDetonate() {
+		return []string{game.ActionDetonate}
+	}
+	if escape, ok := b.bombAndEscape(); ok {
+		return []string{game.ActionPlaceBomb, escape}
+	}
+	if move, ok := b.seek(danger); ok {
+		return []string{move}
+	}
+	return nil
+}
+
+// reverse turns a move around; other actions are left alone
+func reverse(action string) string {
+	for dir, move := range moveActions {
+		if move == action {
+			return moveActions[game.Position{X: -dir.X, Y: -dir.Y}]
+		}
+	}
+	return action
+}
+
+// flee heads for the nearest tile no blast is headed for
+func (b *brain) flee(danger dangerMap) []string {
+	isSafe := func(p game.Position) bool { return danger.at(p) == safe }
+	routes := b.w.explore(b.self.Position, danger, b.stepTime, b.margin, false)
+	target, ok := routes.nearest(isSafe)
+	if !ok {
+		// No way out is sure to work; take the shortest and hope
+		routes = b.w.explore(b.self.Position, danger, b.stepTime, b.margin, true)
+		if target, ok = routes.nearest(isSafe); !ok {
+			return nil
+		}
+	}
+	return []string{moveActions[routes.first[target.Y][target.X]]}
+}
+
+// wander takes a random step, possibly a fatal one
+func (b *brain) wander() []string {
+	var moves []string
+	for _, dir := range directions {
+		if b.w.walkable(game.Position{X: b.self.Position.X + dir.X, Y: b.self.Position.Y + dir.Y}) {
+			moves = append(moves, moveActions[dir])
+		}
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+	return []string{moves[b.rng.Intn(len(moves))]}
+}
+
+// shouldDetonate reports whether the bot's remote-control bombs can go off without
+// hurting it or, with friendly fire on, its teammates
+func (b *brain) shouldDetonate() bool {
+	if !b.self.RemoteControl {
+		return false
+	}
+	spared := append([]*game.Player{b.self}, b.w.teammates(b.self)...)
+	if b.w.teams > 0 && !b.w.friendlyFire {
+		spared = []*game.Player{b.self}
+	}
+	owns := false
+	for i := range b.w.bombs {
+		bomb := &b.w.bombs[i]
+		if bomb.PlayerID != b.self.ID || !bomb.Remote {
+			continue
+		}
+		owns = true
+		for _, tile := range b.w.blast(bomb) {
+			for _, p := range spared {
+				if p.Position == tile {
+					return false
+				}
+			}
+		}
+	}
+	return owns
+}
+
+// bombAndEscape decides whether to drop a bomb where the bot stands: it must hit a
+// block or an opponent, and leave a way to a tile the blast cannot reach. It returns
+// the first step of that way.
+func (b *brain) bombAndEscape() (string, bool) {
+	self := b.self
+	bomb := b.nextBomb()
+	if bomb == nil || !b.worthBombing(b.w.blast(bomb)) {
+		return "", false
+	}
+
+	danger := b.w.danger(self.ID, b.level.Chains, bomb)
+	routes := b.w.explore(self.Position, danger, b.stepTime, b.margin, false)
+	target, ok := routes.nearest(func(p game.Position) bool { return danger.at(p) == safe })
+	if !ok {
+		return "", false
+	}
+	return moveActions[routes.first[target.Y][target.X]], true
+}
+
+// nextBomb is the bomb the bot would drop where it stands, nil if it cannot drop one
+func (b *brain) nextBomb() *game.Bomb {
+	self := b.self
+	if self.ActiveBombs >= self.MaxBombs || b.w.bombAt[self.Position] {
+		return nil
+	}
+	bomb := game.NewBomb(self.Position, self.BombPower, self.ID, b.w.now)
+	bomb.Pierce = self.PierceBombs
+	return bomb
+}
+
+// worthBombing reports whether a blast over tiles is worth it: it destroys a block or
+// catches an opponent (more likely the more aggressive the bot), and spares teammates
+// when friendly fire is on
+func (b *brain) worthBombing(tiles []game.Position) bool {
+	hits := func(players []*game.Player) bool {
+		for _, p := range players {
+			for _, tile := range tiles {
+				if p.Position == tile {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if b.w.friendlyFire && hits(b.w.teammates(b.self)) {
+		return false
+	}
+	if hits(b.w.enemies(b.self)) && b.rng.Float64() < 0.3+b.level.Aggression {
+		return true
+	}
+	for _, tile := range tiles {
+		if b.w.blocks[tile.Y][tile.X] == game.Destructible {
+			return true
+		}
+	}
+	return false
+}
+
+// seek walks towards the best goal in reach: a power-up, a tile to bomb an opponent
+// or a block from, or failing those the nearest opponent. Goals are only picked on
+// tiles no blast is headed for.
+func (b *brain) seek(danger dangerMap) (string, bool) {
+	w, self := b.w, b.self
+	canBomb := self.ActiveBombs < self.MaxBombs
+	enemies := w.enemies(self)
+
+	// Tiles from which a bomb would catch an opponent
+	lines := make(map[game.Position]bool)
+	for _, enemy := range enemies {
+		for _, dir := range directions {
+			for i := 0; i <= self.BombPower; i++ {
+				p := game.Position{X: enemy.Position.X + dir.X*i, Y: enemy.Position.Y + dir.Y*i}
+				if !w.inside(p) || w.blocks[p.Y][p.X] != game.Empty {
+					break
+				}
+				lines[p] = true
+			}
+		}
+	}
+
+	routes := w.explore(self.Position, danger, b.stepTime, b.margin, false)
+	best, bestScore := game.Position{}, 0.0
+	for y, row := range routes.dist {
+		for x, dist := range row {
+			p := game.Position{X: x, Y: y}
+			if dist <= 0 || danger.at(p) != safe {
+				continue
+			}
+			value := 0.0
+			if kind, ok := w.powerUps[p]; ok && kind != game.PowerUpSkull {
+				value += powerUpValue
+			}
+			if canBomb && lines[p] {
+				value += enemyValue * b.level.Aggression
+			}
+			if canBomb && b.nextToBlock(p) {
+				value += blockValue
+			}
+			if hunt := huntValue - float64(nearestDistance(p, enemies)); hunt > 0 {
+				value += hunt * b.level.Aggression
+			}
+			if score := value - float64(dist); score > bestScore {
+				best, bestScore = p, score
+			}
+		}
+	}
+	if bestScore <= 0 {
+		return "", false
+	}
+	return moveActions[routes.first[best.Y][best.X]], true
+}
+
+// nextToBlock reports whether a destructible block is next to p
+func (b *brain) nextToBlock(p game.Position) bool {
+	for _, dir := range directions {
+		next := game.Position{X: p.X + dir.X, Y: p.Y + dir.Y}
+		if b.w.inside(next) && b.w.blocks[next.Y][next.X] == game.Destructible {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestDistance is the number of tiles, ignoring obstacles, from p to the closest
+// of players; a large number if there are none
+func nearestDistance(p game.Position, players []*game.Player) int {
+	best := 1 << 30
+	for _, other := range players {
+		dx, dy := other.Position.X-p.X, other.Position.Y-p.Y
+		if dx < 0 {
+			dx = -dx
+		}
+		if dy < 0 {
+			dy = -dy
+		}
+		if dx+dy < best {
+			best = dx + dy
+		}
+	}
+	return best
+}
@@ -0,0 +1,98 @@
+// Package bot plays the bots of a game. Bots are ordinary players (see
+// game.FillWithBots) whose actions are decided here and sent through
+// game.QueueInput, the same way a client's actions are.
+package bot
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"bomberman-server/internal/game"
+)
+
+// driverInterval is how often the driver looks for bots due to think
+const driverInterval = 20 * time.Millisecond
+
+// Driver plays every bot of one game.
+type Driver struct {
+	game     *game.Game
+	rng      *rand.Rand           // Bots' own random source; the game's is left alone so drops stay reproducible
+	next     map[string]time.Time // Game time of each bot's next decision
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDriver creates a driver for the bots of g.
+func NewDriver(g *game.Game) *Driver {
+	return &Driver{
+		game: g,
+		rng:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		next: make(map[string]time.Time),
+		quit: make(chan struct{}),
+	}
+}
+
+// Run plays the bots until Stop is called.
+func (d *Driver) Run() {
+	ticker := time.NewTicker(driverInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.quit:
+			return
+		case <-ticker.C:
+			d.step()
+		}
+	}
+}
+
+// Stop ends Run.
+func (d *Driver) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.quit)
+	})
+}
+
+// step lets every bot due for a decision think once. Bots only act while the match
+// is running, and the world is only copied when the game has bots in play.
+func (d *Driver) step() {
+	g := d.game
+	g.Mutex.RLock()
+	if g.State != game.GameRunning || !hasBots(g) {
+		g.Mutex.RUnlock()
+		if len(d.next) > 0 {
+			d.next = make(map[string]time.Time)
+		}
+		return
+	}
+	w := newWorld(g)
+	g.Mutex.RUnlock()
+
+	for i := range w.players {
+		self := &w.players[i]
+		level, ok := Levels[self.Bot]
+		if !ok || w.now.Before(d.next[self.ID]) {
+			continue
+		}
+		d.next[self.ID] = w.now.Add(level.Think)
+
+		for _, action := range think(w, self, level, d.rng) {
+			if err := g.QueueInput(game.Input{PlayerID: self.ID, Action: action}); err != nil {
+				log.Printf("Bot %s (%s): %s refused: %v", self.Nickname, self.ID, action, err)
+				break
+			}
+		}
+	}
+}
+
+// hasBots reports whether a bot is still in play in g. Callers must hold g.Mutex.
+func hasBots(g *game.Game) bool {
+	for _, p := range g.Players {
+		if p.Bot != "" && p.IsActive() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package bot
+
+import (
+	"math"
+	"time"
+
+	"bomberman-server/internal/game"
+)
+
+// safe is the danger of a tile no blast is headed for
+const safe = time.Duration(math.MaxInt64)
+
+var directions = []game.Position{{X: 0, Y: -1}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 1, Y: 0}}
+
+// moveActions maps a step to the action that takes it
+var moveActions = map[game.Position]string{
+	{X: 0, Y: -1}: game.ActionMoveUp,
+	{X: 0, Y: 1}:  game.ActionMoveDown,
+	{X: -1, Y: 0}: game.ActionMoveLeft,
+	{X: 1, Y: 0}:  game.ActionMoveRight,
+}
+
+// world is what the bots see of the game on one step of the driver. It is copied
+// under the game's read lock, so the bots can think without holding it.
+type world struct {
+	now          time.Time
+	width        int
+	height       int
+	blocks       [][]game.BlockType
+	bombs        []game.Bomb
+	bombAt       map[game.Position]bool
+	players      []game.Player // Players still in play
+	powerUps     map[game.Position]string
+	closing      []game.Position // Next tiles sudden death closes, in order
+	closeEvery   time.Duration
+	moveInterval time.Duration
+	teams        int
+	friendlyFire bool
+}
+
+// newWorld copies the state of g. Callers must hold g.Mutex.
+func newWorld(g *game.Game) *world {
+	w := &world{
+		now:          g.CurrentTime(),
+		width:        g.Map.Width,
+		height:       g.Map.Height,
+		blocks:       make([][]game.BlockType, len(g.Map.Blocks)),
+		bombs:        make([]game.Bomb, 0, len(g.Bombs)),
+		bombAt:       make(map[game.Position]bool, len(g.Bombs)),
+		powerUps:     make(map[game.Position]string, len(g.PowerUps)),
+		closing:      g.ClosingTiles(game.ClosingTilesShown),
+		closeEvery:   g.Settings.SuddenDeathInterval,
+		moveInterval: g.Settings.MoveInterval,
+		teams:        g.Settings.Teams,
+		friendlyFire: g.Settings.FriendlyFire,
+	}
+	for y, row := range g.Map.Blocks {
+		w.blocks[y] = append([]game.BlockType(nil), row...)
+	}
+	for _, bomb := range g.Bombs {
+		w.bombs = append(w.bombs, *bomb)
+		w.bombAt[bomb.Position] = true
+	}
+	for _, p := range g.PlayersInSlotOrder() {
+		if p.IsActive() {
+			w.players = append(w.players, *p)
+		}
+	}
+	for _, powerUp := range g.PowerUps {
+		w.powerUps[powerUp.Position] = powerUp.Type
+	}
+	return w
+}
+
+func (w *world) inside(p game.Position) bool {
+	return p.X >= 0 && p.X < w.width && p.Y >= 0 && p.Y < w.height
+}
+
+// walkable reports whether a player can step onto p
+func (w *world) walkable(p game.Position) bool {
+	return w.inside(p) && w.blocks[p.Y][p.X] == game.Empty && !w.bombAt[p]
+}
+
+// player returns the player with the given ID, nil if they are out of play
+func (w *world) player(id string) *game.Player {
+	for i := range w.players {
+		if w.players[i].ID == id {
+			return &w.players[i]
+		}
+	}
+	return nil
+}
+
+// enemies lists the players in play that self fights against
+func (w *world) enemies(self *game.Player) []*game.Player {
+	var list []*game.Player
+	for i := range w.players {
+		p := &w.players[i]
+		if p.ID != self.ID && (w.teams == 0 || p.Team != self.Team) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// teammates lists the other players in play on self's team
+func (w *world) teammates(self *game.Player) []*game.Player {
+	var list []*game.Player
+	if w.teams == 0 {
+		return list
+	}
+	for i := range w.players {
+		p := &w.players[i]
+		if p.ID != self.ID && p.Team == self.Team {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// blast lists the tiles a bomb would hit, the same way the game computes it
+func (w *world) blast(bomb *game.Bomb) []game.Position {
+	gm := &game.GameMap{Blocks: w.blocks, Width: w.width, Height: w.height}
+	return bomb.Explode(gm, func(p game.Position) bool { return w.bombAt[p] }).Tiles
+}
+
+// fuse is how long until a bomb goes off. Remote-control bombs of other players can
+// go off at any moment; a bot's own wait for it, so it counts them by their timer.
+func (w *world) fuse(bomb *game.Bomb, selfID string) time.Duration {
+	if bomb.Detonated {
+		return 0
+	}
+	if bomb.Remote && bomb.PlayerID != selfID && w.player(bomb.PlayerID) != nil {
+		return 0
+	}
+	left := bomb.Timer - w.now.Sub(bomb.PlacedAt)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// dangerMap holds, for every tile, how long until a blast or sudden death reaches
+// it; safe if nothing will.
+type dangerMap [][]time.Duration
+
+func (d dangerMap) at(p game.Position) time.Duration {
+	return d[p.Y][p.X]
+}
+
+// danger builds the danger map seen by the bot selfID. extra is a bomb the bot is
+// thinking of placing, or nil. With chains, a bomb caught in another's blast is
+// expected to go off with it.
+func (w *world) danger(selfID string, chains bool, extra *game.Bomb) dangerMap {
+	d := make(dangerMap, w.height)
+	for y := range d {
+		d[y] = make([]time.Duration, w.width)
+		for x := range d[y] {
+			d[y][x] = safe
+		}
+	}
+
+	bombs := make([]*game.Bomb, 0, len(w.bombs)+1)
+	for i := range w.bombs {
+		bombs = append(bombs, &w.bombs[i])
+	}
+	if extra != nil {
+		bombs = append(bombs, extra)
+		w.bombAt[extra.Position] = true
+		defer delete(w.bombAt, extra.Position)
+	}
+
+	fuses := make([]time.Duration, len(bombs))
+	blasts := make([][]game.Position, len(bombs))
+	for i, bomb := range bombs {
+		fuses[i] = w.fuse(bomb, selfID)
+		blasts[i] = w.blast(bomb)
+	}
+
+	// A bomb goes off no later than any bomb whose blast reaches it
+	for changed := chains; changed; {
+		changed = false
+		for i := range bombs {
+			for _, tile := range blasts[i] {
+				for j, other := range bombs {
+					if other.Position == tile && fuses[i] < fuses[j] {
+						fuses[j] = fuses[i]
+						changed = true
+					}
+				}
+			}
+		}
+	}
+
+	for i := range bombs {
+		for _, tile := range blasts[i] {
+			if fuses[i] < d[tile.Y][tile.X] {
+				d[tile.Y][tile.X] = fuses[i]
+			}
+		}
+	}
+	for i, tile := range w.closing {
+		closesIn := time.Duration(i) * w.closeEvery
+		if closesIn < d[tile.Y][tile.X] {
+			d[tile.Y][tile.X] = closesIn
+		}
+	}
+	return d
+}
+
+// routes is a breadth-first search from the bot's tile: for every tile it reached,
+// the number of steps and the first step to take.
+type routes struct {
+	dist  [][]int // -1 where unreached
+	first [][]game.Position
+}
+
+// explore searches the tiles the bot can reach from start. Each step takes stepTime.
+// A tile is only entered if the bot is sure to be off it, with margin to spare,
+// before it is hit. relaxed only asks that the bot gets there before the blast, for
+// when there is no way out that is sure to work.
+func (w *world) explore(start game.Position, d dangerMap, stepTime, margin time.Duration, relaxed bool) *routes {
+	r := &routes{dist: make([][]int, w.height), first: make([][]game.Position, w.height)}
+	for y := range r.dist {
+		r.dist[y] = make([]int, w.width)
+		r.first[y] = make([]game.Position, w.width)
+		for x := range r.dist[y] {
+			r.dist[y][x] = -1
+		}
+	}
+	r.dist[start.Y][start.X] = 0
+
+	queue := []game.Position{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		steps := r.dist[cur.Y][cur.X] + 1
+		arrival := time.Duration(steps) * stepTime
+		for _, dir := range directions {
+			next := game.Position{X: cur.X + dir.X, Y: cur.Y + dir.Y}
+			if !w.walkable(next) || r.dist[next.Y][next.X] >= 0 {
+				continue
+			}
+			danger := d.at(next)
+			if relaxed && danger <= arrival || !relaxed && danger != safe && danger <= arrival+stepTime+margin {
+				continue
+			}
+			r.dist[next.Y][next.X] = steps
+			if cur == start {
+				r.first[next.Y][next.X] = dir
+			} else {
+				r.first[next.Y][next.X] = r.first[cur.Y][cur.X]
+			}
+			queue = append(queue, next)
+		}
+	}
+	return r
+}
+
+// nearest returns the closest reached tile (other than the start) accepted by want
+func (r *routes) nearest(want func(game.Position) bool) (game.Position, bool) {
+	best, found := game.Position{}, false
+	for y, row := range r.dist {
+		for x, dist := range row {
+			p := game.Position{X: x, Y: y}
+			if dist <= 0 || !want(p) {
+				continue
+			}
+			if !found || dist < r.dist[best.Y][best.X] {
+				best, found = p, true
+			}
+		}
+	}
+	return best, found
+}
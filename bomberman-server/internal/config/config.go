@@ -0,0 +1,560 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"bomberman-server/internal/game"
+	"bomberman-server/internal/websocket"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is where the config file is looked up when -config is not given.
+const DefaultPath = "configs/config.yaml"
+
+// envPrefix is prepended to every environment variable override, e.g. BOMBERMAN_PORT.
+const envPrefix = "BOMBERMAN_"
+
+// Config is the typed form of configs/config.yaml.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Game      GameConfig      `yaml:"game"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	Replay    ReplayConfig    `yaml:"replay"`
+	Maps      MapsConfig      `yaml:"maps"`
+}
+
+type ServerConfig struct {
+	Port         int           `yaml:"port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+}
+
+type GameConfig struct {
+	MaxPlayers          int             `yaml:"max_players"`
+	MapSize             MapSize         `yaml:"map_size"`     // Size of generated maps; layouts have their own
+	PowerUpMode         string          `yaml:"powerup_mode"` // "random" or "hidden"
+	PowerUpSpawnRate    float64         `yaml:"powerup_spawn_rate"`
+	HiddenPowerUps      map[string]int  `yaml:"hidden_powerups"` // Power-ups of each type hidden under blocks in hidden mode
+	Rules               string          `yaml:"rules"`           // Built-in rule set: classic, chaos or competitive
+	Map                 string          `yaml:"map"`             // Name of the map layout in maps.dir, or "generated"
+	Generator           GeneratorConfig `yaml:"generator"`       // How "generated" maps are built; their size is map_size
+	CustomRules         *game.Rules     `yaml:"custom_rules"`    // Replaces the built-in rule set when given
+	MoveInterval        time.Duration   `yaml:"move_interval"`
+	Invulnerability     time.Duration   `yaml:"invulnerability"`
+	RespawnOnHit        bool            `yaml:"respawn_on_hit"`
+	Teams               int             `yaml:"teams"`                 // 0 for free-for-all, else the number of teams
+	FriendlyFire        bool            `yaml:"friendly_fire"`         // Whether teammates' bombs hurt in team mode
+	TimeLimit           time.Duration   `yaml:"time_limit"`            // Match time before sudden death; 0 for no limit
+	SuddenDeathInterval time.Duration   `yaml:"sudden_death_interval"` // Time between two tiles closing in sudden death
+	BotDifficulty       string          `yaml:"bot_difficulty"`        // Difficulty of bots filling a lobby: easy, normal or hard
+	TickRate            int             `yaml:"tick_rate"`             // Simulation ticks per second
+	Seed                int64           `yaml:"seed"`                  // Fixed random seed for every round; 0 picks one per round
+}
+
+type MapSize struct {
+	Width  int `yaml:"width"`
+	Height int `yaml:"height"`
+}
+
+type GeneratorConfig struct {
+	Density  float64 `yaml:"density"`  // Share (0-1) of free tiles filled with destructible blocks
+	Symmetry int     `yaml:"symmetry"` // 2 (with 2 spawns) or 4
+	Spawns   int     `yaml:"spawns"`   // 2 to game.MaxGeneratedSpawns (8)
+}
+
+type WebSocketConfig struct {
+	PingInterval   time.Duration `yaml:"ping_interval"`
+	MaxMessageSize int64         `yaml:"max_message_size"`
+	BroadcastRate  int           `yaml:"broadcast_rate"` // State broadcasts per second
+}
+
+type MapsConfig struct {
+	Dir string `yaml:"dir"` // Where map layouts are read from; empty serves only the built-in map
+}
+
+type ReplayConfig struct {
+	Dir string `yaml:"dir"` // Where match recordings are written; empty disables recording
+}
+
+// Default returns the configuration used for any value not set elsewhere.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  120 * time.Second,
+		},
+		Game: GameConfig{
+			MaxPlayers:       game.DefaultSettings().MaxPlayers,
+			MapSize:          MapSize{Width: game.DefaultMapWidth, Height: game.DefaultMapHeight},
+			PowerUpMode:      game.DefaultSettings().PowerUpMode,
+			PowerUpSpawnRate: game.DefaultSettings().PowerUpSpawnRate,
+			HiddenPowerUps:   game.DefaultSettings().HiddenPowerUps,
+			Rules:            game.DefaultSettings().Rules.Name,
+			Map:              game.ClassicLayoutName,
+			Generator: GeneratorConfig{
+				Density:  game.DefaultGeneratorOptions().Density,
+				Symmetry: game.DefaultGeneratorOptions().Symmetry,
+				Spawns:   game.DefaultGeneratorOptions().Spawns,
+			},
+			MoveInterval:        game.DefaultSettings().MoveInterval,
+			Invulnerability:     game.DefaultSettings().Invulnerability,
+			RespawnOnHit:        game.DefaultSettings().RespawnOnHit,
+			Teams:               game.DefaultSettings().Teams,
+			FriendlyFire:        game.DefaultSettings().FriendlyFire,
+			TimeLimit:           game.DefaultSettings().TimeLimit,
+			SuddenDeathInterval: game.DefaultSettings().SuddenDeathInterval,
+			BotDifficulty:       game.DefaultSettings().BotDifficulty,
+			TickRate:            int(time.Second / game.DefaultSettings().TickDuration),
+			Seed:                game.DefaultSettings().Seed,
+		},
+		WebSocket: WebSocketConfig{
+			PingInterval:   websocket.DefaultSettings().PingInterval,
+			MaxMessageSize: websocket.DefaultSettings().MaxMessageSize,
+			BroadcastRate:  int(time.Second / websocket.DefaultSettings().BroadcastInterval),
+		},
+		Replay: ReplayConfig{
+			Dir: "replays",
+		},
+		Maps: MapsConfig{
+			Dir: "maps",
+		},
+	}
+}
+
+// GameSettings returns the rules applied to every new game.
+func (c *Config) GameSettings() game.Settings {
+	rules, _ := c.gameRules() // Checked by Validate
+	var generator *game.GeneratorOptions
+	if c.Game.Map == game.GeneratedLayoutName {
+		opts := c.GeneratorOptions()
+		generator = &opts
+	}
+	return game.Settings{
+		Rules:               rules,
+		MaxPlayers:          c.Game.MaxPlayers,
+		PowerUpMode:         c.Game.PowerUpMode,
+		PowerUpSpawnRate:    c.Game.PowerUpSpawnRate,
+		HiddenPowerUps:      c.Game.HiddenPowerUps,
+		MoveInterval:        c.Game.MoveInterval,
+		Invulnerability:     c.Game.Invulnerability,
+		RespawnOnHit:        c.Game.RespawnOnHit,
+		Teams:               c.Game.Teams,
+		FriendlyFire:        c.Game.FriendlyFire,
+		TimeLimit:           c.Game.TimeLimit,
+		SuddenDeathInterval: c.Game.SuddenDeathInterval,
+		BotDifficulty:       c.Game.BotDifficulty,
+		TickDuration:        time.Second / time.Duration(c.Game.TickRate),
+		Seed:                c.Game.Seed,
+		Generator:           generator,
+	}
+}
+
+// GeneratorOptions returns how generated maps are built
+func (c *Config) GeneratorOptions() game.GeneratorOptions {
+	return game.GeneratorOptions{
+		Width:    c.Game.MapSize.Width,
+		Height:   c.Game.MapSize.Height,
+		Density:  c.Game.Generator.Density,
+		Symmetry: c.Game.Generator.Symmetry,
+		Spawns:   c.Game.Generator.Spawns,
+	}
+}
+
+// gameRules returns the custom rules if any, else the named built-in rule set
+func (c *Config) gameRules() (game.Rules, error) {
+	if c.Game.CustomRules != nil {
+		rules := *c.Game.CustomRules
+		if rules.Name == "" {
+			rules.Name = game.RulesCustom
+		}
+		return rules, rules.Validate()
+	}
+	return game.RulesByName(c.Game.Rules)
+}
+
+// WebSocketSettings returns the limits applied to every websocket client.
+func (c *Config) WebSocketSettings() websocket.Settings {
+	return websocket.Settings{
+		PingInterval:      c.WebSocket.PingInterval,
+		MaxMessageSize:    c.WebSocket.MaxMessageSize,
+		BroadcastInterval: time.Second / time.Duration(c.WebSocket.BroadcastRate),
+	}
+}
+
+// Addr returns the listen address for the HTTP server.
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Server.Port)
+}
+
+// Load builds the configuration from defaults, the config file, environment
+// variables and command line flags, in increasing order of precedence.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", DefaultPath, "path to the YAML config file")
+	port := fs.Int("port", 0, "HTTP port to listen on")
+	readTimeout := fs.Duration("read-timeout", 0, "HTTP read timeout")
+	writeTimeout := fs.Duration("write-timeout", 0, "HTTP write timeout")
+	idleTimeout := fs.Duration("idle-timeout", 0, "HTTP idle timeout")
+	maxPlayers := fs.Int("max-players", 0, "maximum players per game")
+	mapWidth := fs.Int("map-width", 0, "width in tiles of generated maps")
+	mapHeight := fs.Int("map-height", 0, "height in tiles of generated maps")
+	spawnRate := fs.Float64("powerup-spawn-rate", 0, "chance (0-1) that a destroyed block drops a power-up")
+	rules := fs.String("rules", "", "rule set: "+strings.Join(game.RuleNames(), ", "))
+	powerUpMode := fs.String("powerup-mode", "", `how power-ups appear: "random" drops or "hidden" under blocks`)
+	hiddenPowerUps := fs.String("hidden-powerups", "", "power-ups hidden in hidden mode, e.g. speed=4,bomb=6,flame=6")
+	invulnerability := fs.Duration("invulnerability", 0, "protection after losing a life")
+	respawnOnHit := fs.Bool("respawn-on-hit", false, "send players back to their start slot after losing a life")
+	teams := fs.Int("teams", 0, "number of teams (0 for free-for-all)")
+	friendlyFire := fs.Bool("friendly-fire", false, "let teammates' bombs hurt in team mode")
+	timeLimit := fs.Duration("time-limit", 0, "match time before sudden death (0 for no limit)")
+	suddenDeathInterval := fs.Duration("sudden-death-interval", 0, "time between two tiles closing in sudden death")
+	botDifficulty := fs.String("bot-difficulty", "", "difficulty of bots filling a lobby: "+strings.Join(game.BotDifficulties(), ", "))
+	tickRate := fs.Int("tick-rate", 0, "simulation ticks per second")
+	seed := fs.Int64("seed", 0, "fixed random seed for every round (0 picks a new one per round)")
+	broadcastRate := fs.Int("broadcast-rate", 0, "game state broadcasts per second")
+	moveInterval := fs.Duration("move-interval", 0, "time between player steps at base speed")
+	pingInterval := fs.Duration("ping-interval", 0, "interval between websocket pings")
+	maxMessageSize := fs.Int64("max-message-size", 0, "maximum size in bytes of an incoming websocket message")
+	mapName := fs.String("map", "", `name of the map layout to play on, or "generated" for a new map every round`)
+	mapDensity := fs.Float64("map-density", 0, "share (0-1) of free tiles given destructible blocks on generated maps")
+	mapSymmetry := fs.Int("map-symmetry", 0, "symmetry of generated maps: 2 or 4")
+	mapSpawns := fs.Int("map-spawns", 0, fmt.Sprintf("spawns on generated maps: 2 to %d", game.MaxGeneratedSpawns))
+	mapsDir := fs.String("maps-dir", "", "directory of map layouts (empty serves only the built-in map)")
+	replayDir := fs.String("replay-dir", "", "directory for match recordings (empty disables recording)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	explicitPath := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicitPath = true
+		}
+	})
+	if envPath := os.Getenv(envPrefix + "CONFIG"); envPath != "" && !explicitPath {
+		*path = envPath
+		explicitPath = true
+	}
+
+	if err := cfg.loadFile(*path); err != nil {
+		// The default file is optional; an explicitly requested one is not
+		if explicitPath || !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	// Only flags given on the command line override the file and environment
+	var countsErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = *port
+		case "read-timeout":
+			cfg.Server.ReadTimeout = *readTimeout
+		case "write-timeout":
+			cfg.Server.WriteTimeout = *writeTimeout
+		case "idle-timeout":
+			cfg.Server.IdleTimeout = *idleTimeout
+		case "max-players":
+			cfg.Game.MaxPlayers = *maxPlayers
+		case "map-width":
+			cfg.Game.MapSize.Width = *mapWidth
+		case "map-height":
+			cfg.Game.MapSize.Height = *mapHeight
+		case "powerup-spawn-rate":
+			cfg.Game.PowerUpSpawnRate = *spawnRate
+		case "rules":
+			cfg.Game.Rules = *rules
+		case "powerup-mode":
+			cfg.Game.PowerUpMode = *powerUpMode
+		case "hidden-powerups":
+			cfg.Game.HiddenPowerUps, countsErr = parseCounts(*hiddenPowerUps)
+		case "invulnerability":
+			cfg.Game.Invulnerability = *invulnerability
+		case "respawn-on-hit":
+			cfg.Game.RespawnOnHit = *respawnOnHit
+		case "teams":
+			cfg.Game.Teams = *teams
+		case "friendly-fire":
+			cfg.Game.FriendlyFire = *friendlyFire
+		case "time-limit":
+			cfg.Game.TimeLimit = *timeLimit
+		case "sudden-death-interval":
+			cfg.Game.SuddenDeathInterval = *suddenDeathInterval
+		case "bot-difficulty":
+			cfg.Game.BotDifficulty = *botDifficulty
+		case "tick-rate":
+			cfg.Game.TickRate = *tickRate
+		case "seed":
+			cfg.Game.Seed = *seed
+		case "broadcast-rate":
+			cfg.WebSocket.BroadcastRate = *broadcastRate
+		case "move-interval":
+			cfg.Game.MoveInterval = *moveInterval
+		case "ping-interval":
+			cfg.WebSocket.PingInterval = *pingInterval
+		case "max-message-size":
+			cfg.WebSocket.MaxMessageSize = *maxMessageSize
+		case "map":
+			cfg.Game.Map = *mapName
+		case "map-density":
+			cfg.Game.Generator.Density = *mapDensity
+		case "map-symmetry":
+			cfg.Game.Generator.Symmetry = *mapSymmetry
+		case "map-spawns":
+			cfg.Game.Generator.Spawns = *mapSpawns
+		case "maps-dir":
+			cfg.Maps.Dir = *mapsDir
+		case "replay-dir":
+			cfg.Replay.Dir = *replayDir
+		}
+	})
+	if countsErr != nil {
+		return nil, fmt.Errorf("-hidden-powerups: %w", countsErr)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile decodes the YAML file at path on top of the current values.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides values from BOMBERMAN_* environment variables.
+func (c *Config) applyEnv() error {
+	ints := map[string]*int{
+		"PORT":           &c.Server.Port,
+		"MAX_PLAYERS":    &c.Game.MaxPlayers,
+		"MAP_WIDTH":      &c.Game.MapSize.Width,
+		"MAP_HEIGHT":     &c.Game.MapSize.Height,
+		"MAP_SYMMETRY":   &c.Game.Generator.Symmetry,
+		"MAP_SPAWNS":     &c.Game.Generator.Spawns,
+		"TICK_RATE":      &c.Game.TickRate,
+		"TEAMS":          &c.Game.Teams,
+		"BROADCAST_RATE": &c.WebSocket.BroadcastRate,
+	}
+	for name, dst := range ints {
+		if v, ok := lookupEnv(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", envPrefix, name, err)
+			}
+			*dst = n
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"READ_TIMEOUT":          &c.Server.ReadTimeout,
+		"WRITE_TIMEOUT":         &c.Server.WriteTimeout,
+		"IDLE_TIMEOUT":          &c.Server.IdleTimeout,
+		"MOVE_INTERVAL":         &c.Game.MoveInterval,
+		"INVULNERABILITY":       &c.Game.Invulnerability,
+		"TIME_LIMIT":            &c.Game.TimeLimit,
+		"SUDDEN_DEATH_INTERVAL": &c.Game.SuddenDeathInterval,
+		"PING_INTERVAL":         &c.WebSocket.PingInterval,
+	}
+	for name, dst := range durations {
+		if v, ok := lookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", envPrefix, name, err)
+			}
+			*dst = d
+		}
+	}
+
+	if v, ok := lookupEnv("POWERUP_SPAWN_RATE"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%sPOWERUP_SPAWN_RATE: %w", envPrefix, err)
+		}
+		c.Game.PowerUpSpawnRate = f
+	}
+
+	if v, ok := lookupEnv("MAP_DENSITY"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%sMAP_DENSITY: %w", envPrefix, err)
+		}
+		c.Game.Generator.Density = f
+	}
+
+	if v, ok := lookupEnv("RULES"); ok {
+		c.Game.Rules = v
+	}
+
+	if v, ok := lookupEnv("MAP"); ok {
+		c.Game.Map = v
+	}
+
+	if v, ok := lookupEnv("POWERUP_MODE"); ok {
+		c.Game.PowerUpMode = v
+	}
+
+	if v, ok := lookupEnv("BOT_DIFFICULTY"); ok {
+		c.Game.BotDifficulty = v
+	}
+
+	if v, ok := lookupEnv("HIDDEN_POWERUPS"); ok {
+		counts, err := parseCounts(v)
+		if err != nil {
+			return fmt.Errorf("%sHIDDEN_POWERUPS: %w", envPrefix, err)
+		}
+		c.Game.HiddenPowerUps = counts
+	}
+
+	if v, ok := lookupEnv("RESPAWN_ON_HIT"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%sRESPAWN_ON_HIT: %w", envPrefix, err)
+		}
+		c.Game.RespawnOnHit = b
+	}
+
+	if v, ok := lookupEnv("FRIENDLY_FIRE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%sFRIENDLY_FIRE: %w", envPrefix, err)
+		}
+		c.Game.FriendlyFire = b
+	}
+
+	if v, ok := lookupEnv("SEED"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%sSEED: %w", envPrefix, err)
+		}
+		c.Game.Seed = n
+	}
+
+	if v, ok := lookupEnv("MAX_MESSAGE_SIZE"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%sMAX_MESSAGE_SIZE: %w", envPrefix, err)
+		}
+		c.WebSocket.MaxMessageSize = n
+	}
+
+	// Set but empty serves only the built-in map, so this one is not read through lookupEnv
+	if v, ok := os.LookupEnv(envPrefix + "MAPS_DIR"); ok {
+		c.Maps.Dir = strings.TrimSpace(v)
+	}
+
+	// Set but empty disables recording, so this one is not read through lookupEnv
+	if v, ok := os.LookupEnv(envPrefix + "REPLAY_DIR"); ok {
+		c.Replay.Dir = strings.TrimSpace(v)
+	}
+
+	return nil
+}
+
+// parseCounts parses a list like "speed=4,bomb=6" into a map
+func parseCounts(s string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not of the form name=count", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", item, err)
+		}
+		counts[strings.TrimSpace(name)] = n
+	}
+	return counts, nil
+}
+
+func lookupEnv(name string) (string, bool) {
+	v, ok := os.LookupEnv(envPrefix + name)
+	return strings.TrimSpace(v), ok && strings.TrimSpace(v) != ""
+}
+
+// Validate reports every invalid value at once so startup fails with a useful message.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+
+	check(c.Game.MaxPlayers >= 2 && c.Game.MaxPlayers <= game.MAX_PLAYERS,
+		"game.max_players must be between 2 and %d, got %d", game.MAX_PLAYERS, c.Game.MaxPlayers)
+	check(c.Game.PowerUpSpawnRate >= 0 && c.Game.PowerUpSpawnRate <= 1, "game.powerup_spawn_rate must be between 0 and 1, got %v", c.Game.PowerUpSpawnRate)
+	if err := game.ValidatePowerUpMode(c.Game.PowerUpMode, c.Game.HiddenPowerUps); err != nil {
+		check(false, "game.powerup_mode: %v", err)
+	}
+	if _, err := c.gameRules(); err != nil {
+		if c.Game.CustomRules != nil {
+			check(false, "game.custom_rules: %v", err)
+		} else {
+			check(false, "game.rules: %v", err)
+		}
+	}
+
+	if err := game.ValidateTeams(c.Game.Teams); err != nil {
+		check(false, "game.teams: %v", err)
+	}
+	check(c.Game.Teams <= c.Game.MaxPlayers, "game.teams must not exceed game.max_players (%d), got %d", c.Game.MaxPlayers, c.Game.Teams)
+	check(c.Game.Map != "", "game.map must not be empty")
+	if c.Game.Map == game.GeneratedLayoutName {
+		if err := c.GeneratorOptions().Validate(); err != nil {
+			check(false, "game.generator: %v", err)
+		}
+	}
+	check(c.Game.MoveInterval > 0, "game.move_interval must be positive")
+	check(c.Game.Invulnerability >= 0, "game.invulnerability must not be negative")
+	check(c.Game.TimeLimit >= 0, "game.time_limit must not be negative")
+	check(c.Game.SuddenDeathInterval > 0, "game.sudden_death_interval must be positive")
+	if err := game.ValidateBotDifficulty(c.Game.BotDifficulty); err != nil {
+		check(false, "game.bot_difficulty: %v", err)
+	}
+	check(c.Game.TickRate >= 1 && c.Game.TickRate <= 240, "game.tick_rate must be between 1 and 240, got %d", c.Game.TickRate)
+
+	check(c.WebSocket.PingInterval > 0, "websocket.ping_interval must be positive")
+	check(c.WebSocket.BroadcastRate >= 1 && c.WebSocket.BroadcastRate <= 60,
+		"websocket.broadcast_rate must be between 1 and 60, got %d", c.WebSocket.BroadcastRate)
+	check(c.WebSocket.MaxMessageSize > 0, "websocket.max_message_size must be positive, got %d", c.WebSocket.MaxMessageSize)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
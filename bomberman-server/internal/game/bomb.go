@@ -1,6 +1,7 @@
 package game
 
 import (
+	"sort"
 	"time"
 )
 
@@ -11,6 +12,21 @@ type Bomb struct {
 	PlayerID string
 	PlacedAt time.Time
 	Timer    time.Duration
+	Seq      uint64 `json:"-"` // Placement order within the game
+	Remote   bool   // Waits for its owner's detonate action instead of the timer
+	Pierce   bool   // The blast goes through destructible blocks
+
+	// Set by the owner's detonate action; the bomb explodes on the next bomb pass
+	Detonated bool `json:"-"`
+
+	// Direction of a kicked bomb while it slides, zero when it is still, and when it
+	// last moved a tile
+	Sliding     Position  `json:"-"`
+	LastSlideAt time.Time `json:"-"`
+
+	// OwnerOnTile is true until the placing player steps off the bomb.
+	// Until then the bomb does not block them.
+	OwnerOnTile bool `json:"-"`
 }
 
 type Explosion struct {
@@ -18,20 +34,33 @@ type Explosion struct {
 	Range    int        `json:"range"`
 	Tiles    []Position `json:"tiles"`
 	PlayerID string     `json:"playerId"`
+	ChainID  string     `json:"chainId"`
+	Depth    int        `json:"depth"` // 0 for the bomb that started the chain, 1 for bombs it set off, ...
+}
+
+// ChainExplosion groups every explosion set off by a single bomb running out of time.
+type ChainExplosion struct {
+	ID         string       `json:"id"`
+	Explosions []*Explosion `json:"explosions"` // In detonation order
+	Tiles      []Position   `json:"tiles"`      // Union of all explosion tiles
 }
 
-func NewBomb(pos Position, power int, playerID string) *Bomb {
+func NewBomb(pos Position, power int, playerID string, placedAt time.Time) *Bomb {
 	return &Bomb{
 		ID:       GenerateUUID(),
 		Position: pos,
 		Power:    power,
 		PlayerID: playerID,
-		PlacedAt: time.Now(),
+		PlacedAt: placedAt,
 		Timer:    3 * time.Second,
+
+		OwnerOnTile: true,
 	}
 }
 
-func (b *Bomb) Explode(gameMap *GameMap) *Explosion {
+// Explode computes the blast of the bomb. isBomb reports whether another bomb sits on a
+// tile; the blast reaches that tile (so the bomb is set off) but does not go beyond it.
+func (b *Bomb) Explode(gameMap *GameMap, isBomb func(Position) bool) *Explosion {
 	explosion := &Explosion{
 		Center:   b.Position,
 		Range:    b.Power,
@@ -61,8 +90,13 @@ func (b *Bomb) Explode(gameMap *GameMap) *Explosion {
 
 			explosion.Tiles = append(explosion.Tiles, pos)
 
-			// Stop if destructible — it will be destroyed, but don't go beyond it
-			if block == Destructible {
+			// Stop if destructible — it will be destroyed, but don't go beyond it unless piercing
+			if block == Destructible && !b.Pierce {
+				break
+			}
+
+			// Stop at another bomb — it will be set off, but don't go beyond it
+			if isBomb(pos) {
 				break
 			}
 		}
@@ -71,3 +105,22 @@ func (b *Bomb) Explode(gameMap *GameMap) *Explosion {
 	return explosion
 }
 
+// IsSliding reports whether the bomb was kicked and is still moving
+func (b *Bomb) IsSliding() bool {
+	return b.Sliding != (Position{})
+}
+
+// BlocksPlayer reports whether the bomb stops the given player from walking onto its tile
+func (b *Bomb) BlocksPlayer(playerID string) bool {
+	return !(b.OwnerOnTile && b.PlayerID == playerID)
+}
+
+// sortBombs orders bombs by placement so detonation order does not depend on map iteration
+func sortBombs(bombs []*Bomb) {
+	sort.Slice(bombs, func(i, j int) bool {
+		if !bombs[i].PlacedAt.Equal(bombs[j].PlacedAt) {
+			return bombs[i].PlacedAt.Before(bombs[j].PlacedAt)
+		}
+		return bombs[i].Seq < bombs[j].Seq
+	})
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Bot difficulties. The bots themselves are driven from outside the game (see the
+// bot package) and act through QueueInput like everyone else.
+const (
+	BotEasy   = "easy"
+	BotNormal = "normal"
+	BotHard   = "hard"
+)
+
+// BotDifficulties lists the difficulties bots can play at
+func BotDifficulties() []string {
+	return []string{BotEasy, BotNormal, BotHard}
+}
+
+// ValidateBotDifficulty checks a bot difficulty name
+func ValidateBotDifficulty(difficulty string) error {
+	for _, name := range BotDifficulties() {
+		if difficulty == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown bot difficulty %q (want one of %s)", difficulty, strings.Join(BotDifficulties(), ", "))
+}
+
+// botIDPrefix starts the ID of every bot, so they never collide with joined players
+const botIDPrefix = "bot-"
+
+// FillWithBots adds bots to every free slot of the lobby and returns how many it
+// added. An empty difficulty means Settings.BotDifficulty. A full lobby starts its
+// countdown as usual, so a single player can start a match this way.
+func (g *Game) FillWithBots(difficulty string) (int, error) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	if difficulty == "" {
+		difficulty = g.Settings.BotDifficulty
+	}
+	if err := ValidateBotDifficulty(difficulty); err != nil {
+		return 0, err
+	}
+	if g.State != GameWaiting {
+		return 0, errors.New("bots can only be added in the lobby")
+	}
+
+	added := 0
+	for len(g.Players) < g.Capacity() {
+		player, err := g.addPlayer(botIDPrefix+GenerateUUID(), "Bot")
+		if err != nil {
+			if added == 0 {
+				return 0, err
+			}
+			break
+		}
+		player.Bot = difficulty
+		player.Nickname = fmt.Sprintf("Bot %d (%s)", player.Number, difficulty)
+		added++
+	}
+	if added == 0 {
+		return 0, errors.New("lobby is full")
+	}
+	log.Printf("Game %s: added %d %s bots", g.ID, added, difficulty)
+	return added, nil
+}
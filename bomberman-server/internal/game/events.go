@@ -0,0 +1,64 @@
+package game
+
+// Event is a one-off notification produced by the game loop (e.g. an explosion chain)
+// that the hub forwards to clients as a message of the same type.
+type Event struct {
+	Type     string
+	Payload  interface{}
+	PlayerID string // Only sent to this player's connections; empty means everyone
+}
+
+const (
+	EventExplosionChain   = "explosion_chain"
+	EventPlayerEliminated = "player_eliminated"
+	EventMatchResult      = "match_result"
+	EventMoveResult       = "move_result"
+	EventPowerUpDestroyed = "powerup_destroyed"
+)
+
+// Causes of elimination reported in EliminationEvent
+const (
+	CauseBomb        = "bomb"         // Killed by another player's bomb
+	CauseSelf        = "self"         // Killed by their own bomb
+	CauseDisconnect  = "disconnect"   // Did not reconnect within the grace period
+	CauseSuddenDeath = "sudden_death" // Caught under a tile closed by sudden death
+)
+
+// EliminationEvent is broadcast when a player loses their last life
+type EliminationEvent struct {
+	PlayerID     string `json:"playerId"`
+	PlayerName   string `json:"playerName"`
+	PlayerNumber int    `json:"playerNumber"`
+	KillerID     string `json:"killerId,omitempty"`
+	KillerName   string `json:"killerName,omitempty"`
+	Cause        string `json:"cause"`
+}
+
+// PowerUpDestroyedEvent is broadcast when an explosion destroys a power-up lying on the map
+type PowerUpDestroyedEvent struct {
+	ID       string   `json:"id"` // Key of the power-up in the game state
+	Type     string   `json:"type"`
+	Position Position `json:"position"`
+	ChainID  string   `json:"chainId"`  // Explosion chain that destroyed it
+	PlayerID string   `json:"playerId"` // Owner of the bomb
+}
+
+// emit queues an event for the hub. Callers must hold g.Mutex.
+func (g *Game) emit(eventType string, payload interface{}) {
+	g.events = append(g.events, Event{Type: eventType, Payload: payload})
+}
+
+// emitTo queues an event for a single player's connections. Callers must hold g.Mutex.
+func (g *Game) emitTo(playerID, eventType string, payload interface{}) {
+	g.events = append(g.events, Event{Type: eventType, Payload: payload, PlayerID: playerID})
+}
+
+// DrainEvents returns the events queued since the last call and clears the queue.
+func (g *Game) DrainEvents() []Event {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	events := g.events
+	g.events = nil
+	return events
+}
@@ -1,9 +1,11 @@
 package game
 
 import (
+	"crypto/subtle"
 	"errors"
 	"log"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 )
@@ -19,10 +21,12 @@ const (
 )
 
 const PLAYER_MAX_LIVES = 3              // Define max lives for a player
+const MAX_PLAYERS = 10                  // Hard cap on players per game; a map also needs a spawn for each
 const LOBBY_JOIN_WINDOW_SECONDS = 20    // Time in seconds for lobby to remain open after 2nd player joins
 const GAME_START_COUNTDOWN_SECONDS = 10 // Time in seconds for the game to start
 const GAME_RESET_COUNTDOWN_SECONDS = 5  // Time in seconds for the game to reset
 const DISCONNECT_GRACE_PERIOD = 10 * time.Second // Grace period for reconnections
+const POWERUP_SPAWN_GRACE = 500 * time.Millisecond // Power-ups this young survive explosions, so a blast never destroys its own drop
 
 type TimedExplosion struct {
 	*Explosion
@@ -35,6 +39,7 @@ type Game struct {
 	Players   map[string]*Player
 	Bombs     map[string]*Bomb
 	PowerUps  map[string]PowerUp
+	Settings  Settings
 	State     GameState
 	StartTime time.Time
 	Mutex     sync.RWMutex
@@ -43,40 +48,116 @@ type Game struct {
 	CountdownTimer     time.Time
 	WaitingTimer       time.Time
 	ResetTimer         time.Time        // Timer for the reset countdown
-	NextPlayerNumber   int              // ✅ NEW
 	Explosions         []TimedExplosion `json:"explosions"`
 	InitialPlayerCount int              // Number of players when the game started
+	Result             *MatchResult     // Outcome of the last finished match, nil until then
+	SuddenDeath        bool             // The time limit is up and the arena is closing, see suddendeath.go
+
+	events []Event // Pending notifications for the hub, see DrainEvents
+
+	// Actions waiting for the next tick; guarded by inputMutex so queuing never waits on Update
+	inputs       []Input
+	queuedInputs map[string]int // Number of inputs in inputs per player
+	inputMutex   sync.Mutex
+
+	// Simulated time: the game is at epoch + Tick*Settings.TickDuration (see Now)
+	Tick      uint64
+	epoch     time.Time
+	bombCount uint64 // Placement order of bombs, for deterministic detonation order
+
+	powerUpCount uint64 // Power-ups placed so far, numbering their IDs
+
+	// Tiles sudden death closes, in order, and how many of them are closed
+	spiral []Position
+	closed int
+
+	// Random source for power-up drops. Seed is picked for every round (see seedRound)
+	// and reported with the state and the match result so a match can be reproduced.
+	Seed int64
+	rng  *rand.Rand
+
+	// Match being recorded, see recording.go
+	recording   *Recording
+	recordStart uint64 // Tick at which the recorded match started
+
+	// OnRecording receives every finished match recording. It is called with g.Mutex
+	// held, so it must not block or call back into the game.
+	OnRecording func(*Recording)
 }
 
-// NewGame creates a new game instance
-func NewGame() *Game {
-	return &Game{
+// NewGame creates a new game instance with the given rules. It fails if the map of
+// the first round cannot be built.
+func NewGame(settings Settings) (*Game, error) {
+	g := &Game{
 		ID:                 GenerateUUID(),
-		Map:                NewGameMap(),
 		Players:            make(map[string]*Player),
 		Bombs:              make(map[string]*Bomb),
 		PowerUps:           make(map[string]PowerUp),
+		Settings:           settings,
+		epoch:              time.Now(), // Re-anchored by NewLoop
 		State:              GameWaiting,
-		NextPlayerNumber:   1, // ✅ Start from 1
 		InitialPlayerCount: 0, // Initialize
 	}
+	g.seedRound()
+	gm, err := g.newMap()
+	if err != nil {
+		return nil, err
+	}
+	g.Map = gm
+	return g, nil
+}
+
+// newMap builds the map of a new round. It draws from the round's random source, so
+// callers seed the round first. Callers must hold g.Mutex.
+func (g *Game) newMap() (*GameMap, error) {
+	layout := g.Settings.Layout
+	if g.Settings.Generator != nil {
+		generated, err := GenerateLayout(*g.Settings.Generator, g.rng)
+		if err != nil {
+			return nil, err
+		}
+		layout = generated
+	}
+	return g.buildMap(layout), nil
+}
+
+// buildMap lays out a map, the classic one if layout is nil, and hides the power-ups
+// of hidden mode under its blocks. Callers must hold g.Mutex.
+func (g *Game) buildMap(layout *Layout) *GameMap {
+	var hidden map[string]int
+	if g.Settings.PowerUpMode == PowerUpModeHidden {
+		hidden = g.Settings.HiddenPowerUps
+	}
+	if layout == nil {
+		layout = ClassicLayout()
+	}
+	return NewGameMap(layout, hidden, g.rng)
 }
 
 // AddPlayer adds a new player to the game or re-activates an existing one.
 func (g *Game) AddPlayer(id, nickname string) (*Player, error) {
 	g.Mutex.Lock()
 	defer g.Mutex.Unlock()
+	return g.addPlayer(id, nickname)
+}
 
+// addPlayer is AddPlayer for callers already holding g.Mutex
+func (g *Game) addPlayer(id, nickname string) (*Player, error) {
 	// Check if player already exists (rejoin attempt)
 	if existingPlayer, ok := g.Players[id]; ok {
 		log.Printf("Player %s (%s) is rejoining.", nickname, id)
 		existingPlayer.Nickname = nickname // Update nickname if changed
 		existingPlayer.IsConnected = true   // Mark as connected
 		existingPlayer.DisconnectedAt = time.Time{} // Clear disconnect time
+		existingPlayer.LastInputSeq = 0             // A new connection numbers its inputs from 1 again
+		g.recordBetweenTicks(id, ActionReconnect, nickname)
 
 		// If rejoining in the waiting state, reset their lives
 		if g.State == GameWaiting {
-			existingPlayer.Lives = PLAYER_MAX_LIVES // Reset lives
+			existingPlayer.Lives = g.Settings.Rules.Lives // Reset lives
+			existingPlayer.ResetStats(g.Settings.Rules)
+			existingPlayer.Eliminated = false
+			existingPlayer.Stats = PlayerStats{}
 			log.Printf("Player %s (%s) rejoining lobby, lives reset to %d.", nickname, id, existingPlayer.Lives)
 		} else {
 			// Policy for rejoining a game in progress (Countdown, Running, Finished)
@@ -94,33 +175,37 @@ func (g *Game) AddPlayer(id, nickname string) (*Player, error) {
 	}
 
 	// Prevent joining if lobby is full
-	if len(g.Players) >= 4 {
+	if len(g.Players) >= g.Capacity() {
 		return nil, errors.New("lobby is full")
 	}
 
 	// Prevent joining if lobby window is active and has expired
-	if g.State == GameWaiting && !g.WaitingTimer.IsZero() && time.Now().After(g.WaitingTimer) {
+	if g.State == GameWaiting && !g.WaitingTimer.IsZero() && g.now().After(g.WaitingTimer) {
 		return nil, errors.New("lobby join window has closed")
 	}
 
-	// Fixed slot list, ordered
-	slots := []struct {
-		X, Y int
-	}{
-		{1, 2},   // Player 1
-		{13, 2},  // Player 2
-		{1, 12},  // Player 3
-		{13, 12}, // Player 4
-	}
+	// Spawn points of the map, in player number order. The lowest number nobody has
+	// is taken, so slots freed by players leaving the lobby are handed out again.
+	slots := g.Map.Spawns
 
-	slotIndex := len(g.Players)
-	if slotIndex >= len(slots) {
+	taken := make(map[int]bool, len(g.Players))
+	for _, p := range g.Players {
+		taken[p.Number] = true
+	}
+	slotIndex := 0
+	for slotIndex < g.Capacity() && taken[slotIndex+1] {
+		slotIndex++
+	}
+	if slotIndex >= g.Capacity() {
 		return nil, errors.New("no slot available")
 	}
 	slot := slots[slotIndex]
 
-	player := NewPlayer(id, nickname, slot.X, slot.Y) // NewPlayer should initialize lives to PLAYER_MAX_LIVES
+	player := NewPlayer(id, nickname, slot.X, slot.Y)
+	player.Lives = g.Settings.Rules.Lives
+	player.ResetStats(g.Settings.Rules)
 	player.Number = slotIndex + 1
+	g.assignTeam(player)
 	// Ensure IsConnected is true and DisconnectedAt is zeroed by NewPlayer or set here
 	player.IsConnected = true
 	player.DisconnectedAt = time.Time{}
@@ -129,19 +214,19 @@ func (g *Game) AddPlayer(id, nickname string) (*Player, error) {
 	g.Players[id] = player
 	g.Map.PlacePlayer(player, slot.X, slot.Y)
 
-	log.Printf("✅ Assigned new player %s -> Number: %d | Pos: (%d,%d)", nickname, player.Number, slot.X, slot.Y)
+	log.Printf("✅ Assigned new player %s -> Number: %d | Team: %d | Pos: (%d,%d)", nickname, player.Number, player.Team, slot.X, slot.Y)
 
 	// Start lobby join timer if this is the second player and game is waiting
 	if len(g.Players) == 2 && g.State == GameWaiting && g.WaitingTimer.IsZero() {
-		g.WaitingTimer = time.Now().Add(LOBBY_JOIN_WINDOW_SECONDS * time.Second)
+		g.WaitingTimer = g.now().Add(LOBBY_JOIN_WINDOW_SECONDS * time.Second)
 		log.Printf("Lobby join window started for %d seconds. Ends at: %v", LOBBY_JOIN_WINDOW_SECONDS, g.WaitingTimer)
 	}
 
-	// If 4 players join while waiting, immediately move to countdown
-	if len(g.Players) == 4 && g.State == GameWaiting {
-		log.Printf("Lobby full with 4 players. Moving to game countdown.")
+	// If the lobby fills up while waiting, immediately move to countdown
+	if len(g.Players) == g.Capacity() && g.State == GameWaiting {
+		log.Printf("Lobby full with %d players. Moving to game countdown.", len(g.Players))
 		g.State = GameCountdown
-		g.CountdownTimer = time.Now().Add(GAME_START_COUNTDOWN_SECONDS * time.Second)
+		g.CountdownTimer = g.now().Add(GAME_START_COUNTDOWN_SECONDS * time.Second)
 		if !g.WaitingTimer.IsZero() {
 			g.WaitingTimer = time.Time{} // Clear lobby join timer
 		}
@@ -150,60 +235,376 @@ func (g *Game) AddPlayer(id, nickname string) (*Player, error) {
 	return player, nil
 }
 
-// PlaceBomb places a bomb for a player
-func (g *Game) PlaceBomb(playerID string) error {
-	g.Mutex.Lock()
-	defer g.Mutex.Unlock()
+// Capacity is how many players the game takes: Settings.MaxPlayers, or fewer if the
+// map has fewer spawn points. Callers must hold g.Mutex.
+func (g *Game) Capacity() int {
+	if len(g.Map.Spawns) < g.Settings.MaxPlayers {
+		return len(g.Map.Spawns)
+	}
+	return g.Settings.MaxPlayers
+}
+
+// now returns the simulated time of the current tick. Callers must hold g.Mutex.
+func (g *Game) now() time.Time {
+	return g.epoch.Add(time.Duration(g.Tick) * g.Settings.TickDuration)
+}
+
+// Now returns the simulated time of the current tick
+func (g *Game) Now() time.Time {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	return g.now()
+}
+
+// CurrentTime is Now for callers already holding g.Mutex
+func (g *Game) CurrentTime() time.Time {
+	return g.now()
+}
+
+// ErrInvalidSession is returned when a session token does not belong to the player
+var ErrInvalidSession = errors.New("invalid session token")
+
+// ErrPlayerEliminated is returned when an eliminated player tries to act
+var ErrPlayerEliminated = errors.New("eliminated players cannot act")
+
+// IsEliminated reports whether the player has been knocked out of the current game
+func (g *Game) IsEliminated(playerID string) bool {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	player, ok := g.Players[playerID]
+	return ok && player.Eliminated
+}
+
+// HasPlayer reports whether a player with the given ID is in the game
+func (g *Game) HasPlayer(playerID string) bool {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	_, ok := g.Players[playerID]
+	return ok
+}
 
+// Authenticate checks that token is the session token issued to the player when they joined
+func (g *Game) Authenticate(playerID, token string) error {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+
+	player, ok := g.Players[playerID]
+	if !ok {
+		return errors.New("player not found")
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(player.SessionToken), []byte(token)) != 1 {
+		return ErrInvalidSession
+	}
+	return nil
+}
+
+// placeBomb places a bomb for a player. Callers must hold g.Mutex.
+func (g *Game) placeBomb(playerID string) error {
 	player, exists := g.Players[playerID]
 	if !exists {
 		return errors.New("player not found")
 	}
+	if !player.IsActive() {
+		return ErrPlayerEliminated
+	}
+
+	if g.bombAt(player.Position) != nil {
+		return errors.New("a bomb is already placed here")
+	}
 
-	bomb := player.PlaceBomb(g.Map)
+	bomb := player.PlaceBomb(g.Map, g.now())
 	if bomb == nil {
 		return errors.New("cannot place more bombs")
 	}
+	g.bombCount++
+	bomb.Seq = g.bombCount
 
 	g.Bombs[bomb.ID] = bomb
+	player.Stats.BombsPlaced++
 	return nil
 }
 
-// MovePlayer moves a player in the specified direction
-func (g *Game) MovePlayer(playerID string, dx, dy int) error {
-	g.Mutex.Lock()
-	defer g.Mutex.Unlock()
+// MoveResult tells the client what the server did with a move request
+type MoveResult string
+
+const (
+	MoveApplied MoveResult = "moved"   // The player moved
+	MoveBlocked MoveResult = "blocked" // The target tile is a wall, block or bomb
+	MoveKicked  MoveResult = "kicked"  // The player stayed put and kicked the bomb in front of them
+	MoveQueued  MoveResult = "queued"  // The player is on cooldown; the move runs when it ends
+	MoveDropped MoveResult = "dropped" // The player is on cooldown and already has a queued move
+)
 
+// movePlayer moves a player in the specified direction. A player can only take one step
+// per movement cooldown (see Player.MoveCooldown); one early move is queued and applied
+// by Update once the cooldown ends, anything beyond that is dropped. seq is the
+// sequence number of the input, acknowledged again when a queued move runs.
+// Callers must hold g.Mutex.
+func (g *Game) movePlayer(playerID string, dx, dy int, seq uint64) (MoveResult, error) {
 	player, exists := g.Players[playerID]
 	if !exists {
-		return errors.New("player not found")
+		return MoveDropped, errors.New("player not found")
+	}
+	if !player.IsActive() {
+		return MoveDropped, ErrPlayerEliminated
 	}
 
-	player.Move(dx, dy, g.Map)
+	if player.Curse == CurseReverse {
+		dx, dy = -dx, -dy
+	}
 
-	// Check if player moved into a power-up
+	now := g.now()
+	if !player.CanMove(now, g.Settings.MoveInterval) {
+		if player.PendingMove != nil {
+			return MoveDropped, nil
+		}
+		player.PendingMove = &Position{X: dx, Y: dy}
+		player.PendingMoveSeq = seq
+		return MoveQueued, nil
+	}
+
+	player.PendingMove = nil
+	return g.applyMove(player, dx, dy, now), nil
+}
+
+// applyMove performs a single step for a player whose cooldown has ended
+func (g *Game) applyMove(player *Player, dx, dy int, now time.Time) MoveResult {
+	// Bombs are solid, except for the owner who has not yet stepped off theirs
+	target := Position{X: player.Position.X + dx, Y: player.Position.Y + dy}
+	if bomb := g.bombAt(target); bomb != nil && bomb.BlocksPlayer(player.ID) {
+		if player.CanKick && g.kickBomb(bomb, Position{X: dx, Y: dy}, now) {
+			player.LastMoveAt = now
+			return MoveKicked
+		}
+		return MoveBlocked
+	}
+
+	if !player.Move(dx, dy, g.Map) {
+		return MoveBlocked
+	}
+	player.LastMoveAt = now
+
+	for _, bomb := range g.Bombs {
+		if bomb.PlayerID == player.ID && bomb.Position != player.Position {
+			bomb.OwnerOnTile = false
+		}
+	}
+
+	g.collectPowerUps(player, now)
+	g.spreadCurse(player)
+
+	return MoveApplied
+}
+
+// collectPowerUps gives a player the power-ups on their tile, in ID order so a
+// skull always draws the same curse from the seeded random source
+func (g *Game) collectPowerUps(player *Player, now time.Time) {
+	ids := make([]string, 0)
 	for id, powerUp := range g.PowerUps {
-		if player.Position.X == powerUp.Position.X && player.Position.Y == powerUp.Position.Y {
-			player.ApplyPowerUp(powerUp)
-			delete(g.PowerUps, id)
+		if powerUp.Position == player.Position {
+			ids = append(ids, id)
 		}
 	}
+	sort.Strings(ids)
 
+	for _, id := range ids {
+		powerUp := g.PowerUps[id]
+		if powerUp.Type == PowerUpSkull {
+			player.SetCurse(CurseTypes[g.rng.Intn(len(CurseTypes))], now.Add(CurseDuration))
+		} else {
+			player.ApplyPowerUp(powerUp, g.Settings.Rules)
+		}
+		player.Stats.PowerUpsCollected++
+		delete(g.PowerUps, id)
+	}
+}
+
+// spreadCurse passes a curse on when a player steps onto a tile with another player:
+// the uncursed one catches it, with the time left, and the cursed one is rid of it
+func (g *Game) spreadCurse(mover *Player) {
+	for _, other := range g.PlayersInSlotOrder() {
+		if other == mover || !other.IsActive() || other.Position != mover.Position {
+			continue
+		}
+		from, to := mover, other
+		if mover.Curse == "" {
+			from, to = other, mover
+		}
+		if from.Curse == "" || to.Curse != "" {
+			continue
+		}
+		to.SetCurse(from.Curse, from.CurseUntil)
+		from.SetCurse("", time.Time{})
+		return
+	}
+}
+
+// processCurses lifts expired curses and makes players with diarrhea drop bombs.
+// Callers must hold g.Mutex.
+func (g *Game) processCurses(now time.Time) {
+	for _, player := range g.PlayersInSlotOrder() {
+		if player.Curse != "" && !now.Before(player.CurseUntil) {
+			player.SetCurse("", time.Time{})
+		}
+		if player.Curse == CurseDiarrhea && g.State == GameRunning {
+			g.placeBomb(player.ID) // Fails quietly while out of bombs or on a bomb
+		}
+	}
+}
+
+// kickBomb starts a bomb sliding in a direction, if the tile beyond it is free.
+// Callers must hold g.Mutex.
+func (g *Game) kickBomb(bomb *Bomb, dir Position, now time.Time) bool {
+	if bomb.IsSliding() || !g.bombCanEnter(Position{X: bomb.Position.X + dir.X, Y: bomb.Position.Y + dir.Y}) {
+		return false
+	}
+	bomb.Sliding = dir
+	bomb.LastSlideAt = now
+	bomb.OwnerOnTile = false
+	return true
+}
+
+// bombCanEnter reports whether a bomb can slide or land on a tile: on the map, free
+// of blocks and bombs, and (for sliding) not occupied by a player
+func (g *Game) bombCanEnter(pos Position) bool {
+	if !g.Map.IsValidPosition(pos) || !g.Map.IsEmpty(pos) || g.bombAt(pos) != nil {
+		return false
+	}
+	for _, player := range g.Players {
+		if player.IsActive() && player.Position == pos {
+			return false
+		}
+	}
+	return true
+}
+
+// slideBombs moves each kicked bomb one tile per BombSlideInterval until it hits
+// something. Callers must hold g.Mutex.
+func (g *Game) slideBombs(now time.Time) {
+	bombs := make([]*Bomb, 0, len(g.Bombs))
+	for _, bomb := range g.Bombs {
+		bombs = append(bombs, bomb)
+	}
+	sortBombs(bombs)
+	for _, bomb := range bombs {
+		if !bomb.IsSliding() || now.Sub(bomb.LastSlideAt) < BombSlideInterval {
+			continue
+		}
+		next := Position{X: bomb.Position.X + bomb.Sliding.X, Y: bomb.Position.Y + bomb.Sliding.Y}
+		if !g.bombCanEnter(next) {
+			bomb.Sliding = Position{}
+			continue
+		}
+		bomb.Position = next
+		bomb.LastSlideAt = now
+	}
+}
+
+// punchBomb throws the bomb in front of a player PunchDistance tiles over anything in
+// between. If the tile it reaches is taken it keeps going until it finds a free one;
+// a bomb that would leave the map is not thrown. Callers must hold g.Mutex.
+func (g *Game) punchBomb(playerID string) error {
+	player, exists := g.Players[playerID]
+	if !exists {
+		return errors.New("player not found")
+	}
+	if !player.IsActive() {
+		return ErrPlayerEliminated
+	}
+	if !player.CanPunch {
+		return errors.New("player cannot punch")
+	}
+
+	dir := player.Facing()
+	bomb := g.bombAt(Position{X: player.Position.X + dir.X, Y: player.Position.Y + dir.Y})
+	if bomb == nil {
+		return errors.New("no bomb to punch")
+	}
+
+	for distance := PunchDistance; ; distance++ {
+		landing := Position{X: bomb.Position.X + dir.X*distance, Y: bomb.Position.Y + dir.Y*distance}
+		if !g.Map.IsValidPosition(landing) {
+			return errors.New("bomb would leave the map")
+		}
+		if g.Map.IsEmpty(landing) && g.bombAt(landing) == nil {
+			bomb.Position = landing
+			bomb.Sliding = Position{}
+			bomb.OwnerOnTile = false
+			return nil
+		}
+	}
+}
+
+// detonateRemote sets off every remote-control bomb of a player on the next bomb pass.
+// Callers must hold g.Mutex.
+func (g *Game) detonateRemote(playerID string) error {
+	player, exists := g.Players[playerID]
+	if !exists {
+		return errors.New("player not found")
+	}
+	if !player.IsActive() {
+		return ErrPlayerEliminated
+	}
+	for _, bomb := range g.Bombs {
+		if bomb.Remote && bomb.PlayerID == playerID {
+			bomb.Detonated = true
+		}
+	}
 	return nil
 }
 
-// Update updates the game state
+// bombDue reports whether a bomb explodes on this pass: remote-control bombs wait for
+// their owner, unless the owner is out of play, in which case the fuse applies
+func (g *Game) bombDue(bomb *Bomb, now time.Time) bool {
+	if bomb.Detonated {
+		return true
+	}
+	if bomb.Remote {
+		if owner, ok := g.Players[bomb.PlayerID]; ok && owner.IsActive() {
+			return false
+		}
+	}
+	return now.Sub(bomb.PlacedAt) >= bomb.Timer
+}
+
+// processPendingMoves applies queued moves of players whose cooldown has ended and
+// tells each player where the move took them
+func (g *Game) processPendingMoves(now time.Time) {
+	// Fixed order so simultaneous moves resolve the same way every time
+	for _, player := range g.PlayersInSlotOrder() {
+		if player.PendingMove == nil || !player.IsActive() || !player.CanMove(now, g.Settings.MoveInterval) {
+			continue
+		}
+		move := *player.PendingMove
+		player.PendingMove = nil
+		result := g.applyMove(player, move.X, move.Y, now)
+		g.emitTo(player.ID, EventMoveResult, MoveResultEvent{
+			Seq:       player.PendingMoveSeq,
+			Status:    string(result),
+			Position:  player.Position,
+			Direction: player.Direction,
+		})
+	}
+}
+
+// Update advances the simulation by one tick. It is driven by Loop at a fixed rate;
+// all game logic reads time from the tick count, never from the wall clock.
 func (g *Game) Update() {
     g.Mutex.Lock()
     defer g.Mutex.Unlock()
-    now := time.Now()
+    g.Tick++
+    now := g.now()
+
+    // Apply the actions players sent since the last tick, in arrival order
+    g.processInputs()
 
     // Handle disconnected players
-    for _, p := range g.Players { // Changed playerID to _
+    for _, p := range g.PlayersInSlotOrder() { // Slot order keeps the elimination events reproducible
         if !p.IsConnected && !p.DisconnectedAt.IsZero() && now.Sub(p.DisconnectedAt) > DISCONNECT_GRACE_PERIOD {
             if p.Lives > 0 {
                 log.Printf("Player %s (%s) disconnect grace period expired. Marking as dead.", p.Nickname, p.ID)
                 p.Lives = 0
+                g.eliminatePlayer(p, "", CauseDisconnect, now)
                 // Player remains in g.Players but with 0 lives.
                 // The game logic for checking alive players will handle game over conditions.
             }
@@ -212,6 +613,17 @@ func (g *Game) Update() {
         }
     }
 
+    // Apply moves that were queued while players were on cooldown
+    g.processPendingMoves(now)
+
+    // Wear off curses and apply their per-tick effects
+    g.processCurses(now)
+
+    // Expire post-hit protection
+    for _, p := range g.Players {
+        p.Invulnerable = p.IsInvulnerable(now)
+    }
+
     // Handle game state transitions
     switch g.State {
     case GameWaiting:
@@ -230,32 +642,39 @@ func (g *Game) Update() {
             g.State = GameRunning
             g.StartTime = now
             g.InitialPlayerCount = len(g.Players) // Set initial player count
+            g.Result = nil
+            g.clearSuddenDeath()
+            g.balanceTeams()
+            for _, p := range g.Players {
+                p.Stats = PlayerStats{} // Lobby antics do not count
+            }
+            g.startRecording(now)
         }
 
     case GameRunning:
         // Process bombs
         g.processBombs()
 
+        // Close the arena once the time limit is up
+        g.processSuddenDeath(now)
+
         // Check if game is over
-        alivePlayers := 0
         if len(g.Players) > 0 { // Only check if there were players to begin with
-            for _, p := range g.Players {
-                if p.Lives > 0 {
-                    alivePlayers++
-                }
-            }
-            // Game ends if 0 or 1 player is alive (and game had started with players)
-            if alivePlayers <= 1 {
-                log.Printf("Game finished. Alive players: %d", alivePlayers)
-                g.State = GameFinished
+            // Game ends once 0 or 1 player (or team) is left standing
+            if aliveSides := g.sidesAlive(); aliveSides <= 1 {
+                log.Printf("Game finished. Players or teams left standing: %d", aliveSides)
+                g.finishMatch(now)
                 // Instead of just GameFinished, transition to GameResetting
                 // g.State = GameResetting
                 // g.ResetTimer = now.Add(GAME_RESET_COUNTDOWN_SECONDS * time.Second)
                 // log.Printf("Game finished. Resetting in %d seconds.", GAME_RESET_COUNTDOWN_SECONDS)
+            } else if g.suddenDeathOver() {
+                log.Printf("Game timed out with %d players or teams left standing", aliveSides)
+                g.finishMatch(now)
             }
         } else if !g.StartTime.IsZero() { // If game started but no players (e.g. all disconnected)
             log.Println("Game finished as no players are left.")
-            g.State = GameFinished
+            g.finishMatch(now)
             // g.State = GameResetting
             // g.ResetTimer = now.Add(GAME_RESET_COUNTDOWN_SECONDS * time.Second)
             // log.Printf("Game ended with no players. Resetting in %d seconds.", GAME_RESET_COUNTDOWN_SECONDS)
@@ -305,8 +724,9 @@ func (g *Game) ResetGame() {
 	}
 
 	log.Println("External request to reset game. Initiating reset countdown.")
+	g.stopRecording() // A match cut short is still saved, without a result
 	g.State = GameResetting
-	g.ResetTimer = time.Now().Add(GAME_RESET_COUNTDOWN_SECONDS * time.Second)
+	g.ResetTimer = g.now().Add(GAME_RESET_COUNTDOWN_SECONDS * time.Second)
 	// No need to call resetGameInternal() here, Update() will handle it when timer expires
 }
 
@@ -315,7 +735,15 @@ func (g *Game) ResetGame() {
 func (g *Game) resetGameInternal() {
 	// This function assumes g.Mutex is already locked if called from Update()
 	// or ResetGame(). If called directly, ensure locking.
-	g.Map = NewGameMap()                     // Reset the map
+	g.stopRecording()
+	g.seedRound()                            // The next round gets its own seed, unless the rules fix it
+	if gm, err := g.newMap(); err != nil {
+		// The room was created with this map, so this is a generator bug; keep playing
+		log.Printf("Game %s: %v; using the classic map this round", g.ID, err)
+		g.Map = g.buildMap(nil)
+	} else {
+		g.Map = gm // Reset the map
+	}
 	g.Players = make(map[string]*Player)     // Clear players
 	g.Bombs = make(map[string]*Bomb)         // Clear bombs
 	g.PowerUps = make(map[string]PowerUp)    // Clear power-ups
@@ -324,87 +752,272 @@ func (g *Game) resetGameInternal() {
 	g.CountdownTimer = time.Time{}           // Reset countdown timer
 	g.WaitingTimer = time.Time{}             // Reset lobby waiting timer
 	g.ResetTimer = time.Time{}               // Clear the reset timer itself
-	g.NextPlayerNumber = 1                   // Reset player number assignments
 	g.Explosions = make([]TimedExplosion, 0) // Clear explosions
 	g.InitialPlayerCount = 0                 // Reset initial player count
+	g.Result = nil                           // Forget the last match result
+	g.clearSuddenDeath()                     // The next match starts with the full map
 
 	log.Println("Game has been reset internally.")
 }
 
-// processBombs handles bomb explosions
+// processBombs handles bomb explosions. Every bomb whose timer ran out sets off a chain:
+// bombs caught in its blast detonate in the same tick, and so on.
 func (g *Game) processBombs() {
-	for bombID, bomb := range g.Bombs {
-		if time.Since(bomb.PlacedAt) >= bomb.Timer {
-			// Explode the bomb
-			explosion := bomb.Explode(g.Map)
-			g.processExplosion(explosion)
+	now := g.now()
+
+	// Kicked bombs move before anything explodes
+	g.slideBombs(now)
+
+	expired := make([]*Bomb, 0)
+	for _, bomb := range g.Bombs {
+		if g.bombDue(bomb, now) {
+			expired = append(expired, bomb)
+		}
+	}
+	sortBombs(expired)
+
+	for _, bomb := range expired {
+		// Already detonated as part of an earlier chain this tick
+		if _, exists := g.Bombs[bomb.ID]; !exists {
+			continue
+		}
+
+		chain := g.detonateChain(bomb)
+		g.emit(EventExplosionChain, chain) // Before any elimination it causes
+		g.processChain(chain, now)
+
+		for _, explosion := range chain.Explosions {
 			g.Explosions = append(g.Explosions, TimedExplosion{
 				Explosion: explosion,
-				CreatedAt: time.Now(),
+				CreatedAt: now,
 			})
+		}
+	}
+}
+
+// detonateChain explodes the given bomb and, breadth first, every bomb reached by
+// the resulting blasts. All blasts are computed against the map as it was before the
+// chain, so a block destroyed by one bomb still shields tiles from the others.
+// Each detonated bomb is removed and refunded to its owner.
+func (g *Game) detonateChain(first *Bomb) *ChainExplosion {
+	chain := &ChainExplosion{ID: GenerateUUID()}
+	covered := make(map[Position]bool)
 
-			// Remove the bomb
-			delete(g.Bombs, bombID)
+	queue := []*Bomb{first}
+	queued := map[string]bool{first.ID: true}
 
-			// Update player's active bombs count
+	for depth := 0; len(queue) > 0; depth++ {
+		next := make([]*Bomb, 0)
+
+		for _, bomb := range queue {
+			explosion := bomb.Explode(g.Map, func(pos Position) bool {
+				return pos != bomb.Position && g.bombAt(pos) != nil
+			})
+			explosion.ChainID = chain.ID
+			explosion.Depth = depth
+			chain.Explosions = append(chain.Explosions, explosion)
+
+			delete(g.Bombs, bomb.ID)
 			if player, exists := g.Players[bomb.PlayerID]; exists {
 				player.BombExploded()
 			}
+
+			for _, pos := range explosion.Tiles {
+				if !covered[pos] {
+					covered[pos] = true
+					chain.Tiles = append(chain.Tiles, pos)
+				}
+				for _, other := range g.bombsAt(pos) {
+					if !queued[other.ID] {
+						queued[other.ID] = true
+						next = append(next, other)
+					}
+				}
+			}
 		}
+
+		queue = next
 	}
+
+	return chain
 }
 
-// processExplosion handles the effects of an explosion
-func (g *Game) processExplosion(explosion *Explosion) {
+// bombAt returns the bomb on a tile, or nil if there is none
+func (g *Game) bombAt(pos Position) *Bomb {
+	for _, bomb := range g.Bombs {
+		if bomb.Position == pos {
+			return bomb
+		}
+	}
+	return nil
+}
+
+// bombsAt returns the bombs on a tile, oldest first
+func (g *Game) bombsAt(pos Position) []*Bomb {
+	found := make([]*Bomb, 0)
+	for _, bomb := range g.Bombs {
+		if bomb.Position == pos {
+			found = append(found, bomb)
+		}
+	}
+	sortBombs(found)
+	return found
+}
+
+// processChain applies a whole chain at once: blocks in any blast are destroyed and
+// every player caught in it is hit once, however many blasts overlap their tile.
+func (g *Game) processChain(chain *ChainExplosion, now time.Time) {
+	hit := make(map[string]bool)
+	for _, explosion := range chain.Explosions {
+		g.processExplosion(explosion, hit, now)
+	}
+}
+
+// processExplosion handles the effects of an explosion.
+// Players already in hit are skipped and players hit here are added to it.
+func (g *Game) processExplosion(explosion *Explosion, hit map[string]bool, now time.Time) {
+	// Destroy exposed power-ups. This runs before blocks are destroyed, and drops from
+	// this chain are protected by POWERUP_SPAWN_GRACE, so nothing dropped here is lost.
+	g.destroyPowerUps(explosion, now)
+
 	// Check if any blocks were destroyed
 	for _, pos := range explosion.Tiles {
 		if g.Map.IsDestructible(pos) {
 			g.Map.DestroyBlock(pos)
+			if owner, ok := g.Players[explosion.PlayerID]; ok {
+				owner.Stats.BlocksDestroyed++
+			}
 
-			// 30% chance to spawn a power-up
-			if rand.Float32() < 0.3 {
-				powerUp := SpawnPowerUp(pos)
-				g.PowerUps[GenerateUUID()] = powerUp
+			// Reveal what the block was hiding, or roll for a random drop
+			if powerUpType, ok := g.Map.revealPowerUp(pos); ok {
+				g.placePowerUp(NewPowerUp(powerUpType, pos), now)
+			} else if g.Settings.PowerUpMode == PowerUpModeRandom && g.rng.Float64() < g.Settings.PowerUpSpawnRate {
+				if powerUp, ok := SpawnPowerUp(pos, g.Settings.Rules.DropWeights, g.rng); ok {
+					g.placePowerUp(powerUp, now)
+				}
 			}
 		}
 	}
 
-	// Check if any players were hit
-	for _, player := range g.Players {
+	// Check if any players were hit, in slot order so the events come out the same every run
+	for _, player := range g.PlayersInSlotOrder() {
+		if hit[player.ID] || !player.IsActive() || player.IsInvulnerable(now) || g.spares(explosion.PlayerID, player) {
+			continue
+		}
 		for _, pos := range explosion.Tiles {
 			if player.Position.X == pos.X && player.Position.Y == pos.Y {
-				player.Hit()
+				g.hitPlayer(player, explosion.PlayerID, now)
+				hit[player.ID] = true
 				break
 			}
 		}
 	}
 }
 
-// PlayerNumbers returns a map of player IDs to their assigned numbers
-func (g *Game) PlayerNumbers() map[string]int {
-	g.Mutex.RLock()
-	defer g.Mutex.RUnlock()
-	numbers := make(map[string]int)
-	i := 1
-	for id := range g.Players {
-		numbers[id] = i
-		i++
+// destroyPowerUps removes the power-ups on the tiles of an explosion, except ones
+// that spawned less than POWERUP_SPAWN_GRACE ago, and announces each one.
+func (g *Game) destroyPowerUps(explosion *Explosion, now time.Time) {
+	tiles := make(map[Position]bool, len(explosion.Tiles))
+	for _, pos := range explosion.Tiles {
+		tiles[pos] = true
+	}
+
+	// In ID order so the events come out the same on every run
+	ids := make([]string, 0)
+	for id, powerUp := range g.PowerUps {
+		if tiles[powerUp.Position] && now.Sub(powerUp.SpawnedAt) >= POWERUP_SPAWN_GRACE {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		powerUp := g.PowerUps[id]
+		delete(g.PowerUps, id)
+		g.emit(EventPowerUpDestroyed, PowerUpDestroyedEvent{
+			ID:       id,
+			Type:     powerUp.Type,
+			Position: powerUp.Position,
+			ChainID:  explosion.ChainID,
+			PlayerID: explosion.PlayerID,
+		})
 	}
-	return numbers
 }
 
-// GetPlayerNumber returns the assigned number of a specific player
-func (g *Game) GetPlayerNumber(playerID string) int {
-	g.Mutex.RLock()
-	defer g.Mutex.RUnlock()
-	i := 1
-	for id := range g.Players {
-		if id == playerID {
-			return i
+// hitPlayer takes a life from a player, then protects them for a while and
+// optionally sends them back to their start slot. ownerID is the owner of the bomb.
+func (g *Game) hitPlayer(player *Player, ownerID string, now time.Time) {
+	player.Stats.Deaths++
+	if ownerID == player.ID {
+		player.Stats.Suicides++
+	} else if owner, ok := g.Players[ownerID]; ok {
+		if g.Settings.Teams > 0 && owner.Team == player.Team {
+			owner.Stats.TeamKills++
+		} else {
+			owner.Stats.Kills++
 		}
-		i++
 	}
-	return 0
+
+	if player.Hit() {
+		cause := CauseBomb
+		if ownerID == player.ID {
+			cause = CauseSelf
+		}
+		g.eliminatePlayer(player, ownerID, cause, now)
+		return
+	}
+
+	player.InvulnerableUntil = now.Add(g.Settings.Invulnerability)
+	player.Invulnerable = g.Settings.Invulnerability > 0
+
+	if g.Settings.Rules.LoseOnDeath {
+		player.ResetStats(g.Settings.Rules)
+	}
+
+	if g.Settings.RespawnOnHit {
+		player.Respawn()
+	}
+}
+
+// finishMatch ends a running match and announces the result
+func (g *Game) finishMatch(now time.Time) {
+	g.State = GameFinished
+	g.Result = g.buildMatchResult(now)
+	if g.Result.Draw {
+		log.Printf("Match %s ended in a draw", g.ID)
+	} else if g.Result.WinnerTeam != 0 {
+		log.Printf("Match %s won by team %d", g.ID, g.Result.WinnerTeam)
+	} else {
+		log.Printf("Match %s won by %s (%s)", g.ID, g.Result.WinnerName, g.Result.WinnerID)
+	}
+	g.emit(EventMatchResult, g.Result)
+	g.stopRecording()
+}
+
+// eliminatePlayer takes a player out of play and announces who knocked them out.
+// They stay in g.Players (as a spectator) so the final standings remain available.
+func (g *Game) eliminatePlayer(player *Player, killerID, cause string, now time.Time) {
+	if player.Eliminated {
+		return
+	}
+	player.Eliminated = true
+	player.EliminatedAt = now
+	player.Invulnerable = false
+	player.InvulnerableUntil = time.Time{}
+	player.PendingMove = nil
+
+	event := EliminationEvent{
+		PlayerID:     player.ID,
+		PlayerName:   player.Nickname,
+		PlayerNumber: player.Number,
+		Cause:        cause,
+	}
+	if killer, ok := g.Players[killerID]; ok && killerID != "" {
+		event.KillerID = killer.ID
+		event.KillerName = killer.Nickname
+	}
+	log.Printf("Player %s (%s) eliminated (%s) by %q", player.Nickname, player.ID, cause, event.KillerName)
+	g.emit(EventPlayerEliminated, event)
 }
 
 func (g *Game) GetBombList() []*Bomb {
@@ -420,18 +1033,13 @@ func (g *Game) GetBombList() []*Bomb {
 
 // PlayersInSlotOrder returns players sorted by their Number (slot)
 func (g *Game) PlayersInSlotOrder() []*Player {
-	ordered := make([]*Player, 0, 4)
-	added := map[int]bool{} // prevent duplicates
-
-	for slot := 1; slot <= 4; slot++ {
-		for _, p := range g.Players {
-			if p.Number == slot && !added[p.Number] {
-				ordered = append(ordered, p)
-				added[p.Number] = true
-				break
-			}
-		}
+	ordered := make([]*Player, 0, len(g.Players))
+	for _, p := range g.Players {
+		ordered = append(ordered, p)
 	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Number < ordered[j].Number
+	})
 	return ordered
 }
 
@@ -450,7 +1058,8 @@ func (g *Game) HandlePlayerDisconnect(playerID string) {
     // Don't immediately set lives to 0.
     if player.IsConnected { // Only process if they were marked as connected
         player.IsConnected = false
-        player.DisconnectedAt = time.Now()
+        player.DisconnectedAt = g.now()
+        g.recordBetweenTicks(playerID, ActionDisconnect, "")
         log.Printf("Player %s (%s) disconnected. Grace period of %v started.", player.Nickname, playerID, DISCONNECT_GRACE_PERIOD)
     }
 
@@ -0,0 +1,200 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock stands still; tests drive the game with Update alone
+type fakeClock struct{ now time.Time }
+
+func (c fakeClock) Now() time.Time                       { return c.now }
+func (c fakeClock) After(time.Duration) <-chan time.Time { return nil }
+
+// scriptedInput is an action sent before a given tick
+type scriptedInput struct {
+	tick     int
+	playerID string
+	action   string
+}
+
+// inputScript sends random moves and bombs from every player, drawn from its own
+// source so that every run of a match gets the same script
+func inputScript(playerIDs []string, ticks int) []scriptedInput {
+	actions := []string{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight, ActionPlaceBomb, ActionDetonate}
+	rng := rand.New(rand.NewSource(2024))
+	var script []scriptedInput
+	for tick := 0; tick < ticks; tick++ {
+		for _, id := range playerIDs {
+			if rng.Intn(4) == 0 {
+				script = append(script, scriptedInput{tick: tick, playerID: id, action: actions[rng.Intn(len(actions))]})
+			}
+		}
+	}
+	return script
+}
+
+// tickState is what a test compares of the game after a tick. Bomb, chain and game
+// IDs are left out: they are random by design and nothing depends on their order.
+func tickState(g *Game, events []Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tick %d state %d sudden death %v\n", g.Tick, g.State, g.SuddenDeath)
+	for _, p := range g.PlayersInSlotOrder() {
+		fmt.Fprintf(&b, "player %s #%d team %d at %v lives %d out %v curse %q speed %v bombs %d/%d power %d stats %+v\n",
+			p.ID, p.Number, p.Team, p.Position, p.Lives, p.Eliminated, p.Curse, p.Speed, p.ActiveBombs, p.MaxBombs, p.BombPower, p.Stats)
+	}
+	bombs := make([]*Bomb, 0, len(g.Bombs))
+	for _, bomb := range g.Bombs {
+		bombs = append(bombs, bomb)
+	}
+	sort.Slice(bombs, func(i, j int) bool { return bombs[i].Seq < bombs[j].Seq })
+	for _, bomb := range bombs {
+		fmt.Fprintf(&b, "bomb %d of %s at %v power %d\n", bomb.Seq, bomb.PlayerID, bomb.Position, bomb.Power)
+	}
+	ids := make([]string, 0, len(g.PowerUps))
+	for id := range g.PowerUps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(&b, "power-up %s %s at %v\n", id, g.PowerUps[id].Type, g.PowerUps[id].Position)
+	}
+	fmt.Fprintf(&b, "blocks %v\n", g.Map.Blocks)
+	for _, event := range events {
+		fmt.Fprintf(&b, "event %s to %q", event.Type, event.PlayerID)
+		switch payload := event.Payload.(type) {
+		case MoveResultEvent, EliminationEvent:
+			fmt.Fprintf(&b, " %+v", payload)
+		case PowerUpDestroyedEvent:
+			payload.ChainID = ""
+			fmt.Fprintf(&b, " %+v", payload)
+		}
+		b.WriteString("\n")
+	}
+	if g.Result != nil {
+		fmt.Fprintf(&b, "result winner %q team %d draw %v timeout %v\n", g.Result.WinnerID, g.Result.WinnerTeam, g.Result.Draw, g.Result.Timeout)
+	}
+	return b.String()
+}
+
+// playMatch runs a match from the lobby with the scripted inputs and returns the
+// state after every tick
+func playMatch(t *testing.T, settings Settings, playerIDs []string, script []scriptedInput, ticks int) []string {
+	t.Helper()
+	g, err := NewGame(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewLoop(g, fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	for _, id := range playerIDs {
+		if _, err := g.AddPlayer(id, "Player "+id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	states := make([]string, 0, ticks)
+	finished := false
+	next := 0
+	for tick := 0; tick < ticks; tick++ {
+		for ; next < len(script) && script[next].tick == tick; next++ {
+			input := script[next]
+			g.QueueInput(Input{PlayerID: input.playerID, Seq: uint64(next + 1), Action: input.action})
+		}
+		g.Update()
+		states = append(states, tickState(g, g.DrainEvents()))
+		finished = finished || g.State == GameFinished
+	}
+	if !finished {
+		t.Fatalf("match did not finish in %d ticks", ticks)
+	}
+	return states
+}
+
+func TestUpdateIsReproducible(t *testing.T) {
+	teams := DefaultSettings()
+	teams.Teams = 2
+	teams.FriendlyFire = true
+	hidden := DefaultSettings()
+	hidden.PowerUpMode = PowerUpModeHidden
+	generated := DefaultSettings()
+	opts := DefaultGeneratorOptions()
+	generated.Generator = &opts
+	chaos := DefaultSettings()
+	chaos.Rules = ChaosRules()
+	chaos.PowerUpSpawnRate = 1
+
+	tests := []struct {
+		name     string
+		settings Settings
+		players  []string
+	}{
+		{"duel", DefaultSettings(), []string{"a", "b"}},
+		{"teams with friendly fire", teams, []string{"a", "b", "c", "d"}},
+		{"hidden power-ups", hidden, []string{"a", "b", "c"}},
+		{"generated map", generated, []string{"a", "b", "c", "d"}},
+		{"chaos drops", chaos, []string{"a", "b", "c", "d"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := tc.settings
+			settings.Seed = 77
+			settings.MaxPlayers = len(tc.players)
+			settings.TimeLimit = 20 * time.Second
+			settings.SuddenDeathInterval = 20 * time.Millisecond
+
+			// Countdown, time limit and a sudden death long enough for any map
+			ticks := int((GAME_START_COUNTDOWN_SECONDS*time.Second + settings.TimeLimit + 6*time.Second) / settings.TickDuration)
+			script := inputScript(tc.players, ticks)
+
+			first := playMatch(t, settings, tc.players, script, ticks)
+			second := playMatch(t, settings, tc.players, script, ticks)
+			for tick := range first {
+				if first[tick] != second[tick] {
+					t.Fatalf("runs differ at tick %d:\n%s\nthen:\n%s", tick+1, first[tick], second[tick])
+				}
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Fatal("runs differ")
+			}
+		})
+	}
+}
+
+func TestSimultaneousEliminationsComeInSlotOrder(t *testing.T) {
+	ids := []string{"d", "a", "c", "b"} // Joining in this order gives slots 1 to 4
+	for run := 0; run < 20; run++ {
+		g, err := NewGame(DefaultSettings())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if _, err := g.AddPlayer(id, "Player "+id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// One blast catches every player on their last life
+		explosion := &Explosion{PlayerID: "nobody"}
+		for _, p := range g.PlayersInSlotOrder() {
+			p.Lives = 1
+			explosion.Tiles = append(explosion.Tiles, p.Position)
+		}
+		g.DrainEvents()
+		g.processExplosion(explosion, make(map[string]bool), g.now())
+
+		var got []string
+		for _, event := range g.DrainEvents() {
+			if event.Type == EventPlayerEliminated {
+				got = append(got, event.Payload.(EliminationEvent).PlayerID)
+			}
+		}
+		if !reflect.DeepEqual(got, ids) {
+			t.Fatalf("run %d: eliminated %v, want slot order %v", run, got, ids)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// GeneratedLayoutName names maps built by GenerateLayout; a room using it gets a new
+// map every round
+const GeneratedLayoutName = "generated"
+
+// MaxGeneratedSpawns is the most spawn points GenerateLayout places
+const MaxGeneratedSpawns = 8
+
+// GeneratorOptions control GenerateLayout.
+type GeneratorOptions struct {
+	Width    int     `json:"width" yaml:"width"`       // Odd, from 7 to MaxMapSize
+	Height   int     `json:"height" yaml:"height"`     // Odd, from 7 to MaxMapSize
+	Density  float64 `json:"density" yaml:"density"`   // Share (0-1) of free tiles filled with destructible blocks
+	Symmetry int     `json:"symmetry" yaml:"symmetry"` // 2: point symmetric, for two spawns only; 4: mirrored on both axes
+	Spawns   int     `json:"spawns" yaml:"spawns"`     // 2 to MaxGeneratedSpawns: the corners, then the middle of each side
+}
+
+// DefaultGeneratorOptions returns options giving maps like the classic one.
+func DefaultGeneratorOptions() GeneratorOptions {
+	return GeneratorOptions{
+		Width:    DefaultMapWidth,
+		Height:   DefaultMapHeight,
+		Density:  0.7,
+		Symmetry: 4,
+		Spawns:   4,
+	}
+}
+
+// Validate checks the options. Valid options always generate a playable layout.
+func (o GeneratorOptions) Validate() error {
+	if o.Width < 7 || o.Height < 7 || o.Width > MaxMapSize || o.Height > MaxMapSize || o.Width%2 == 0 || o.Height%2 == 0 {
+		return fmt.Errorf("generated maps must be odd-sized from 7x7 to %dx%d, got %dx%d", MaxMapSize, MaxMapSize, o.Width, o.Height)
+	}
+	if o.Density < 0 || o.Density > 1 {
+		return fmt.Errorf("density must be between 0 and 1, got %v", o.Density)
+	}
+	if o.Symmetry != 2 && o.Symmetry != 4 {
+		return fmt.Errorf("symmetry must be 2 or 4, got %d", o.Symmetry)
+	}
+	if o.Spawns < MinSpawns || o.Spawns > MaxGeneratedSpawns {
+		return fmt.Errorf("generated maps have %d to %d spawns, got %d", MinSpawns, MaxGeneratedSpawns, o.Spawns)
+	}
+	// Point symmetry only pairs each corner with the opposite one, so a third spawn
+	// would not see the same surroundings as the first two
+	if o.Symmetry == 2 && o.Spawns > 2 {
+		return fmt.Errorf("symmetry 2 only keeps 2 spawns fair, got %d spawns", o.Spawns)
+	}
+	return nil
+}
+
+// GenerateLayout builds a map: a border, indestructible pillars on every other tile
+// and destructible blocks drawn from rng at the given density. The blocks are drawn
+// for one half (or quarter) of the map and mirrored onto the rest, so every spawn
+// sees the same surroundings. The tiles next to each spawn are kept clear: an L in
+// the corners and a T in the middle of the sides.
+// The same options and random source always give the same layout.
+func GenerateLayout(opts GeneratorOptions, rng *rand.Rand) (*Layout, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	w, h := opts.Width, opts.Height
+
+	tiles := make([][]byte, h)
+	for y := range tiles {
+		tiles[y] = make([]byte, w)
+		for x := range tiles[y] {
+			switch {
+			case x == 0 || y == 0 || x == w-1 || y == h-1:
+				tiles[y][x] = tileWall
+			case x%2 == 0 && y%2 == 0:
+				tiles[y][x] = tileWall
+			default:
+				tiles[y][x] = tileEmpty
+			}
+		}
+	}
+
+	// Spawns in the order players take them, in pairs facing each other so any even
+	// number of players is symmetric either way: opposite corners first, then the
+	// middle of the top and bottom, then of the left and right
+	spawns := []Position{
+		{X: 1, Y: 1}, {X: w - 2, Y: h - 2}, {X: w - 2, Y: 1}, {X: 1, Y: h - 2},
+		{X: w / 2, Y: 1}, {X: w / 2, Y: h - 2}, {X: 1, Y: h / 2}, {X: w - 2, Y: h / 2},
+	}
+	// The corners are always cleared, the sides a pair at a time, so the clear areas
+	// stay symmetric whatever the number of spawns
+	cleared := 4
+	for cleared < opts.Spawns {
+		cleared += 2
+	}
+	safe := make(map[Position]bool)
+	for _, spawn := range spawns[:cleared] {
+		safe[spawn] = true
+		for _, dir := range directionDeltas {
+			next := Position{X: spawn.X + dir.X, Y: spawn.Y + dir.Y}
+			if tiles[next.Y][next.X] == tileEmpty {
+				safe[next] = true
+			}
+		}
+	}
+
+	// mirrors lists the tiles that must look like (x, y)
+	mirrors := func(x, y int) []Position {
+		if opts.Symmetry == 2 {
+			return []Position{{X: w - 1 - x, Y: h - 1 - y}}
+		}
+		return []Position{{X: w - 1 - x, Y: y}, {X: x, Y: h - 1 - y}, {X: w - 1 - x, Y: h - 1 - y}}
+	}
+	drawn := func(x, y int) bool {
+		if opts.Symmetry == 2 {
+			return y < h/2 || (y == h/2 && x <= w/2)
+		}
+		return x <= w/2 && y <= h/2
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !drawn(x, y) || tiles[y][x] != tileEmpty || safe[Position{X: x, Y: y}] {
+				continue
+			}
+			// Draw for every candidate tile, so the density alone decides the fill
+			if rng.Float64() >= opts.Density {
+				continue
+			}
+			tiles[y][x] = tileDestructible
+			for _, m := range mirrors(x, y) {
+				if tiles[m.Y][m.X] == tileEmpty && !safe[m] {
+					tiles[m.Y][m.X] = tileDestructible
+				}
+			}
+		}
+	}
+
+	for number, spawn := range spawns[:opts.Spawns] {
+		tiles[spawn.Y][spawn.X] = byte('1' + number)
+	}
+
+	layout := &Layout{Name: GeneratedLayoutName, Tiles: make([]string, h)}
+	for y, row := range tiles {
+		layout.Tiles[y] = string(row)
+	}
+	if err := layout.Validate(); err != nil {
+		return nil, fmt.Errorf("generated map is not playable: %w\n%s", err, strings.Join(layout.Tiles, "\n"))
+	}
+	return layout, nil
+}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var generatorCases = []struct {
+	name string
+	opts GeneratorOptions
+}{
+	{"default", DefaultGeneratorOptions()},
+	{"duel", GeneratorOptions{Width: 11, Height: 9, Density: 0.5, Symmetry: 2, Spawns: 2}},
+	{"duel mirrored", GeneratorOptions{Width: 7, Height: 7, Density: 1, Symmetry: 4, Spawns: 2}},
+	{"five spawns", GeneratorOptions{Width: 15, Height: 13, Density: 0.7, Symmetry: 4, Spawns: 5}},
+	{"eight spawns", GeneratorOptions{Width: 25, Height: 19, Density: 0.9, Symmetry: 4, Spawns: 8}},
+}
+
+func TestGenerateLayoutIsReproducible(t *testing.T) {
+	for _, tc := range generatorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, seed := range []int64{1, 42, 1 << 40} {
+				first, err := GenerateLayout(tc.opts, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				second, err := GenerateLayout(tc.opts, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				if !reflect.DeepEqual(first.Tiles, second.Tiles) {
+					t.Errorf("seed %d gave two layouts:\n%v\n%v", seed, first.Tiles, second.Tiles)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateLayoutIsSymmetric(t *testing.T) {
+	for _, tc := range generatorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			layout, err := GenerateLayout(tc.opts, rand.New(rand.NewSource(7)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, h := tc.opts.Width, tc.opts.Height
+
+			// Spawns are empty tiles as far as the surroundings go
+			tile := func(x, y int) byte {
+				if c := layout.Tiles[y][x]; c != tileWall && c != tileDestructible {
+					return tileEmpty
+				}
+				return layout.Tiles[y][x]
+			}
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					mirrors := []Position{{X: w - 1 - x, Y: h - 1 - y}}
+					if tc.opts.Symmetry == 4 {
+						mirrors = append(mirrors, Position{X: w - 1 - x, Y: y}, Position{X: x, Y: h - 1 - y})
+					}
+					for _, m := range mirrors {
+						if tile(x, y) != tile(m.X, m.Y) {
+							t.Fatalf("tile (%d,%d) is %q but its mirror (%d,%d) is %q", x, y, tile(x, y), m.X, m.Y, tile(m.X, m.Y))
+						}
+					}
+				}
+			}
+
+			spawns := layout.Spawns()
+			if len(spawns) != tc.opts.Spawns {
+				t.Fatalf("got %d spawns, want %d", len(spawns), tc.opts.Spawns)
+			}
+			for i := 0; i+1 < len(spawns); i += 2 {
+				a, b := spawns[i], spawns[i+1]
+				if b.X != w-1-a.X || b.Y != h-1-a.Y {
+					t.Errorf("spawn %d at %v does not face spawn %d at %v", i+2, b, i+1, a)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratorOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts GeneratorOptions
+		ok   bool
+	}{
+		{"default", DefaultGeneratorOptions(), true},
+		{"point symmetric duel", GeneratorOptions{Width: 9, Height: 9, Density: 0.5, Symmetry: 2, Spawns: 2}, true},
+		{"point symmetric four", GeneratorOptions{Width: 9, Height: 9, Density: 0.5, Symmetry: 2, Spawns: 4}, false},
+		{"even width", GeneratorOptions{Width: 10, Height: 9, Density: 0.5, Symmetry: 4, Spawns: 2}, false},
+		{"too small", GeneratorOptions{Width: 5, Height: 5, Density: 0.5, Symmetry: 4, Spawns: 2}, false},
+		{"density above 1", GeneratorOptions{Width: 9, Height: 9, Density: 1.5, Symmetry: 4, Spawns: 2}, false},
+		{"symmetry 3", GeneratorOptions{Width: 9, Height: 9, Density: 0.5, Symmetry: 3, Spawns: 2}, false},
+		{"too many spawns", GeneratorOptions{Width: 9, Height: 9, Density: 0.5, Symmetry: 4, Spawns: MaxGeneratedSpawns + 1}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if (err == nil) != tc.ok {
+				t.Errorf("Validate() = %v, want ok %v", err, tc.ok)
+			}
+		})
+	}
+}
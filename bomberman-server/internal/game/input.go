@@ -0,0 +1,124 @@
+package game
+
+import "errors"
+
+// Actions a player can send
+const (
+	ActionMoveUp    = "move_up"
+	ActionMoveDown  = "move_down"
+	ActionMoveLeft  = "move_left"
+	ActionMoveRight = "move_right"
+	ActionPlaceBomb = "place_bomb"
+	ActionDetonate  = "detonate" // Set off the player's remote-control bombs
+	ActionPunch     = "punch"    // Throw the bomb in front of the player
+)
+
+// maxQueuedInputs bounds the inputs of one player waiting for the next tick, so a
+// flooding client can neither grow the queue without limit nor crowd out the others
+const maxQueuedInputs = 32
+
+var (
+	ErrUnknownAction  = errors.New("unknown action")
+	ErrInputQueueFull = errors.New("too many actions, slow down")
+)
+
+// Input is a player action waiting to be applied at the start of the next tick
+type Input struct {
+	PlayerID string
+	Seq      uint64 // Client-side sequence number; 0 means unnumbered
+	Action   string
+}
+
+// MoveResultEvent tells a player what happened to one of their move actions and
+// where the server has them, so the client can reconcile its prediction
+type MoveResultEvent struct {
+	Seq       uint64   `json:"seq"`
+	Status    string   `json:"status"` // moved, blocked, kicked, queued or dropped
+	Position  Position `json:"position"`
+	Direction string   `json:"direction"`
+}
+
+// moveDeltas maps move actions to their direction
+var moveDeltas = map[string]Position{
+	ActionMoveUp:    {X: 0, Y: -1},
+	ActionMoveDown:  {X: 0, Y: 1},
+	ActionMoveLeft:  {X: -1, Y: 0},
+	ActionMoveRight: {X: 1, Y: 0},
+}
+
+// bombActions are the actions that are not moves
+var bombActions = map[string]bool{
+	ActionPlaceBomb: true,
+	ActionDetonate:  true,
+	ActionPunch:     true,
+}
+
+// QueueInput queues a player action. It does not touch the game state: the action
+// is applied by the next Update, in the order actions were queued.
+func (g *Game) QueueInput(input Input) error {
+	if _, isMove := moveDeltas[input.Action]; !isMove && !bombActions[input.Action] {
+		return ErrUnknownAction
+	}
+
+	g.inputMutex.Lock()
+	defer g.inputMutex.Unlock()
+
+	if g.queuedInputs[input.PlayerID] >= maxQueuedInputs {
+		return ErrInputQueueFull
+	}
+	if g.queuedInputs == nil {
+		g.queuedInputs = make(map[string]int)
+	}
+	g.queuedInputs[input.PlayerID]++
+	g.inputs = append(g.inputs, input)
+	return nil
+}
+
+// processInputs applies every queued input. Numbered inputs at or below the player's
+// last applied sequence number are duplicates or arrived out of order and are skipped.
+// Callers must hold g.Mutex.
+func (g *Game) processInputs() {
+	g.inputMutex.Lock()
+	inputs := g.inputs
+	g.inputs = nil
+	g.queuedInputs = nil
+	g.inputMutex.Unlock()
+
+	for _, input := range inputs {
+		player, exists := g.Players[input.PlayerID]
+		if !exists {
+			continue
+		}
+		if input.Seq != 0 {
+			if input.Seq <= player.LastInputSeq {
+				continue
+			}
+			player.LastInputSeq = input.Seq
+		}
+		g.recordInput(input)
+
+		if delta, isMove := moveDeltas[input.Action]; isMove {
+			result, err := g.movePlayer(player.ID, delta.X, delta.Y, input.Seq)
+			if err != nil {
+				continue
+			}
+			g.emitTo(player.ID, EventMoveResult, MoveResultEvent{
+				Seq:       input.Seq,
+				Status:    string(result),
+				Position:  player.Position,
+				Direction: player.Direction,
+			})
+			continue
+		}
+
+		// Rejected actions (limit reached, tile taken, nothing to punch) are simply ignored
+		switch input.Action {
+		case ActionPlaceBomb:
+			g.placeBomb(player.ID)
+		case ActionDetonate:
+			g.detonateRemote(player.ID)
+		case ActionPunch:
+			g.punchBomb(player.ID)
+		}
+	}
+}
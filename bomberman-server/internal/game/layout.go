@@ -0,0 +1,240 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Layout describes a map: its tiles, where players start and any power-ups placed
+// under blocks. In the text form a layout is just the tile rows.
+//
+// Tiles are written one row per string: '#' for indestructible walls, '*' for
+// destructible blocks, ' ' or '.' for empty tiles, and the digits '1'-'9' and '0'
+// (for 10) for spawn points (empty tiles where the player with that number starts).
+type Layout struct {
+	Name     string          `json:"name"`
+	Tiles    []string        `json:"tiles"`
+	PowerUps []HiddenPowerUp `json:"powerUps,omitempty"` // Placed under destructible blocks whatever the power-up mode
+
+	blocks [][]BlockType // Parsed from Tiles by Validate
+	spawns []Position    // In player number order
+}
+
+// Tiles of the layout text format
+const (
+	tileWall         = '#'
+	tileDestructible = '*'
+	tileEmpty        = ' '
+	tileEmptyDot     = '.'
+)
+
+// MinSpawns is the fewest spawn points a layout can have
+const MinSpawns = 2
+
+// ClassicLayoutName names the built-in layout used when a game has none
+const ClassicLayoutName = "classic"
+
+// ClassicLayout returns the built-in 15x15 layout with a spawn in each corner.
+func ClassicLayout() *Layout {
+	layout := &Layout{Name: ClassicLayoutName, Tiles: make([]string, len(predefinedLayout))}
+	for y, row := range predefinedLayout {
+		layout.Tiles[y] = strings.Join(row, "")
+	}
+	// The fixed player slots, as they were before layouts existed
+	for number, slot := range []Position{{X: 1, Y: 2}, {X: 13, Y: 2}, {X: 1, Y: 12}, {X: 13, Y: 12}} {
+		row := []byte(layout.Tiles[slot.Y])
+		row[slot.X] = byte('1' + number)
+		layout.Tiles[slot.Y] = string(row)
+	}
+	if err := layout.Validate(); err != nil {
+		panic("classic layout: " + err.Error())
+	}
+	return layout
+}
+
+// ParseLayoutText reads the text form of a layout: one line per row. Trailing carriage
+// returns are dropped and a trailing empty line is ignored.
+func ParseLayoutText(name string, data []byte) (*Layout, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	layout := &Layout{Name: name, Tiles: strings.Split(text, "\n")}
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// layoutFields has the fields of Layout without its UnmarshalJSON method
+type layoutFields Layout
+
+// ParseLayoutJSON reads the JSON form of a layout. name is used if the file has none.
+func ParseLayoutJSON(name string, data []byte) (*Layout, error) {
+	var fields layoutFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("decoding layout: %w", err)
+	}
+	layout := Layout(fields)
+	if layout.Name == "" {
+		layout.Name = name
+	}
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// UnmarshalJSON parses and validates a layout, so one decoded as part of Settings
+// (e.g. from a recording) is ready to build maps from.
+func (l *Layout) UnmarshalJSON(data []byte) error {
+	var fields layoutFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*l = Layout(fields)
+	return l.Validate()
+}
+
+// Width is the number of columns of the layout
+func (l *Layout) Width() int {
+	if len(l.blocks) == 0 {
+		return 0
+	}
+	return len(l.blocks[0])
+}
+
+// Height is the number of rows of the layout
+func (l *Layout) Height() int {
+	return len(l.blocks)
+}
+
+// Spawns returns the spawn points in player number order
+func (l *Layout) Spawns() []Position {
+	return append([]Position(nil), l.spawns...)
+}
+
+// Validate parses the tiles and checks that the layout is playable:
+//   - it is a rectangle of MinMapSize to MaxMapSize tiles a side, with only known tiles
+//   - spawn points are numbered 1 to n without gaps, with at least MinSpawns of them
+//   - every spawn can reach every other once destructible blocks are blown up
+//   - a player can drop a bomb next to their spawn and take cover from it
+//   - power-ups sit under distinct destructible blocks
+func (l *Layout) Validate() error {
+	if len(l.Tiles) < MinMapSize || len(l.Tiles) > MaxMapSize {
+		return fmt.Errorf("layout %q has %d rows, want %d to %d", l.Name, len(l.Tiles), MinMapSize, MaxMapSize)
+	}
+	width := len(l.Tiles[0])
+	if width < MinMapSize || width > MaxMapSize {
+		return fmt.Errorf("layout %q has %d columns, want %d to %d", l.Name, width, MinMapSize, MaxMapSize)
+	}
+
+	blocks := make([][]BlockType, len(l.Tiles))
+	spawnsByNumber := make(map[int]Position)
+	for y, row := range l.Tiles {
+		if len(row) != width {
+			return fmt.Errorf("layout %q row %d has %d tiles, want %d like row 0", l.Name, y, len(row), width)
+		}
+		blocks[y] = make([]BlockType, len(row))
+		for x := 0; x < len(row); x++ {
+			switch c := row[x]; {
+			case c == tileWall:
+				blocks[y][x] = Indestructible
+			case c == tileDestructible:
+				blocks[y][x] = Destructible
+			case c == tileEmpty || c == tileEmptyDot:
+				blocks[y][x] = Empty
+			case c >= '0' && c <= '9':
+				number := int(c - '0')
+				if number == 0 {
+					number = 10
+				}
+				if _, taken := spawnsByNumber[number]; taken {
+					return fmt.Errorf("layout %q has more than one spawn %d", l.Name, number)
+				}
+				spawnsByNumber[number] = Position{X: x, Y: y}
+				blocks[y][x] = Empty
+			default:
+				return fmt.Errorf("layout %q has unknown tile %q at (%d,%d)", l.Name, c, x, y)
+			}
+		}
+	}
+
+	if len(spawnsByNumber) < MinSpawns {
+		return fmt.Errorf("layout %q has %d spawn points, want at least %d", l.Name, len(spawnsByNumber), MinSpawns)
+	}
+	spawns := make([]Position, len(spawnsByNumber))
+	for number := 1; number <= len(spawnsByNumber); number++ {
+		pos, ok := spawnsByNumber[number]
+		if !ok {
+			return fmt.Errorf("layout %q has no spawn %d; spawns must be numbered from 1 without gaps", l.Name, number)
+		}
+		spawns[number-1] = pos
+	}
+
+	reachable := floodFill(blocks, spawns[0], func(block BlockType) bool { return block != Indestructible })
+	for i, spawn := range spawns {
+		if !reachable[spawn] {
+			return fmt.Errorf("layout %q spawn %d at (%d,%d) cannot reach spawn 1", l.Name, i+1, spawn.X, spawn.Y)
+		}
+		if !hasSafeBombSpot(blocks, spawn) {
+			return fmt.Errorf("layout %q spawn %d at (%d,%d) has nowhere to drop a bomb and take cover", l.Name, i+1, spawn.X, spawn.Y)
+		}
+	}
+
+	seen := make(map[Position]bool)
+	for _, powerUp := range l.PowerUps {
+		pos := powerUp.Position
+		if !isPowerUpType(powerUp.Type) {
+			return fmt.Errorf("layout %q has unknown power-up type %q", l.Name, powerUp.Type)
+		}
+		if pos.X < 0 || pos.Y < 0 || pos.Y >= len(blocks) || pos.X >= len(blocks[pos.Y]) || blocks[pos.Y][pos.X] != Destructible {
+			return fmt.Errorf("layout %q power-up at (%d,%d) is not under a destructible block", l.Name, pos.X, pos.Y)
+		}
+		if seen[pos] {
+			return fmt.Errorf("layout %q has more than one power-up at (%d,%d)", l.Name, pos.X, pos.Y)
+		}
+		seen[pos] = true
+	}
+
+	l.blocks = blocks
+	l.spawns = spawns
+	return nil
+}
+
+// floodFill returns the tiles reachable from start moving through tiles that pass
+func floodFill(blocks [][]BlockType, start Position, passable func(BlockType) bool) map[Position]bool {
+	reached := map[Position]bool{start: true}
+	queue := []Position{start}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, dir := range directionDeltas {
+			next := Position{X: pos.X + dir.X, Y: pos.Y + dir.Y}
+			if next.Y < 0 || next.Y >= len(blocks) || next.X < 0 || next.X >= len(blocks[next.Y]) {
+				continue
+			}
+			if reached[next] || !passable(blocks[next.Y][next.X]) {
+				continue
+			}
+			reached[next] = true
+			queue = append(queue, next)
+		}
+	}
+	return reached
+}
+
+// hasSafeBombSpot reports whether a player starting on spawn, before any block is
+// destroyed, can reach a tile to drop a power 1 bomb on and another tile outside its blast
+func hasSafeBombSpot(blocks [][]BlockType, spawn Position) bool {
+	area := floodFill(blocks, spawn, func(block BlockType) bool { return block == Empty })
+	for bomb := range area {
+		for cover := range area {
+			dx, dy := cover.X-bomb.X, cover.Y-bomb.Y
+			inBlast := (dx == 0 && dy >= -1 && dy <= 1) || (dy == 0 && dx >= -1 && dx <= 1)
+			if !inBlast {
+				return true
+			}
+		}
+	}
+	return false
+}
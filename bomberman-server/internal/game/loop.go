@@ -0,0 +1,78 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCatchUpTicks bounds how many ticks the loop runs back to back after a stall.
+// Anything beyond that is skipped so a long pause does not freeze the server.
+const maxCatchUpTicks = 10
+
+// Clock is the time source of a Loop. It only decides when ticks run; the
+// simulation itself measures time in ticks (see Game.Now), so a replay or test
+// can drive a Game with Update alone and get the same results.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the wall clock.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Loop advances a Game by one Update every Settings.TickDuration, independently
+// of how often the game state is broadcast.
+type Loop struct {
+	game     *Game
+	clock    Clock
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewLoop creates a loop for g and anchors the game's simulated time to clock.
+func NewLoop(g *Game, clock Clock) *Loop {
+	g.Mutex.Lock()
+	g.epoch = clock.Now()
+	g.Mutex.Unlock()
+
+	return &Loop{
+		game:  g,
+		clock: clock,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Run ticks the game until Stop is called. Ticks are scheduled against the start
+// time rather than the previous tick, so a slow tick does not slow the game down.
+func (l *Loop) Run() {
+	step := l.game.Settings.TickDuration
+	start := l.clock.Now()
+	var ticks uint64
+
+	for {
+		wait := start.Add(time.Duration(ticks+1) * step).Sub(l.clock.Now())
+		select {
+		case <-l.quit:
+			return
+		case <-l.clock.After(wait):
+		}
+
+		target := uint64(l.clock.Now().Sub(start) / step)
+		if target-ticks > maxCatchUpTicks {
+			ticks = target - maxCatchUpTicks
+		}
+		for ; ticks < target; ticks++ {
+			l.game.Update()
+		}
+	}
+}
+
+// Stop ends Run.
+func (l *Loop) Stop() {
+	l.stopOnce.Do(func() {
+		close(l.quit)
+	})
+}
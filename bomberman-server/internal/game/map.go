@@ -1,8 +1,16 @@
 package game
 
+import (
+	"math/rand"
+	"sort"
+)
+
+// Map sizes in tiles
 const (
-	MapWidth  = 15
-	MapHeight = 15
+	DefaultMapWidth  = 15 // Size of the classic map, and of generated maps unless configured
+	DefaultMapHeight = 15
+	MinMapSize       = 5  // Smallest width or height of a layout
+	MaxMapSize       = 41 // Largest width or height of a layout
 )
 
 type BlockType int
@@ -17,9 +25,23 @@ const (
 type GameMap struct {
 	Blocks  [][]BlockType `json:"blocks"`
 	Players []*Player     `json:"players"`
+	Width   int           `json:"-"` // Columns of Blocks; sent with the state meta
+	Height  int           `json:"-"` // Rows of Blocks
+	Name    string        `json:"-"` // Name of the layout the map was built from
+	Spawns  []Position    `json:"-"` // Start tiles, in player number order
+
+	// Power-ups waiting under destructible blocks (PowerUpModeHidden). Server-side only,
+	// players find out what a block held when it is destroyed.
+	hidden map[Position]string
 }
 
-// Layout design using string values
+// HiddenPowerUp is a power-up placed under a block when the map was generated
+type HiddenPowerUp struct {
+	Type     string   `json:"type"`
+	Position Position `json:"position"`
+}
+
+// Layout design using string values, served as ClassicLayout
 var predefinedLayout = [][]string{
 	{"#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#"},
 	{"#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#"},
@@ -38,34 +60,76 @@ var predefinedLayout = [][]string{
 	{"#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#", "#"},
 }
 
-// Converts the above string layout to BlockType
-func (gm *GameMap) generateMap() {
-	for y := 0; y < MapHeight; y++ {
-		for x := 0; x < MapWidth; x++ {
-			switch predefinedLayout[y][x] {
-			case "#":
-				gm.Blocks[y][x] = Indestructible
-			case "*":
-				gm.Blocks[y][x] = Destructible
-			case " ":
-				gm.Blocks[y][x] = Empty
-			default:
-				gm.Blocks[y][x] = Empty
+// NewGameMap builds a map from a validated layout. The layout's own power-ups are
+// placed first, then the given number of power-ups of each type are hidden under
+// randomly chosen remaining destructible blocks. hidden may be nil.
+func NewGameMap(layout *Layout, hidden map[string]int, rng *rand.Rand) *GameMap {
+	gm := &GameMap{
+		Blocks:  make([][]BlockType, len(layout.blocks)),
+		Players: make([]*Player, 0),
+		Width:   layout.Width(),
+		Height:  layout.Height(),
+		Name:    layout.Name,
+		Spawns:  layout.Spawns(),
+		hidden:  make(map[Position]string),
+	}
+	for y, row := range layout.blocks {
+		gm.Blocks[y] = append([]BlockType(nil), row...)
+	}
+	for _, powerUp := range layout.PowerUps {
+		gm.hidden[powerUp.Position] = powerUp.Type
+	}
+	gm.hidePowerUps(hidden, rng)
+	return gm
+}
+
+// hidePowerUps places power-ups under distinct destructible blocks that hide nothing
+// yet. If there are more power-ups than blocks, the ones that do not fit are left out.
+func (gm *GameMap) hidePowerUps(counts map[string]int, rng *rand.Rand) {
+	candidates := make([]Position, 0)
+	for y := range gm.Blocks {
+		for x := range gm.Blocks[y] {
+			if _, taken := gm.hidden[Position{X: x, Y: y}]; gm.Blocks[y][x] == Destructible && !taken {
+				candidates = append(candidates, Position{X: x, Y: y})
 			}
 		}
 	}
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	// Types in a fixed order so the same seed always hides the same power-ups
+	next := 0
+	for _, powerUpType := range PowerUpTypes {
+		for i := 0; i < counts[powerUpType] && next < len(candidates); i++ {
+			gm.hidden[candidates[next]] = powerUpType
+			next++
+		}
+	}
 }
 
-func NewGameMap() *GameMap {
-	gm := &GameMap{
-		Blocks:  make([][]BlockType, MapHeight),
-		Players: make([]*Player, 0),
+// revealPowerUp removes and returns the power-up hidden at pos, if any
+func (gm *GameMap) revealPowerUp(pos Position) (string, bool) {
+	powerUpType, ok := gm.hidden[pos]
+	if ok {
+		delete(gm.hidden, pos)
 	}
-	for i := range gm.Blocks {
-		gm.Blocks[i] = make([]BlockType, MapWidth)
+	return powerUpType, ok
+}
+
+// HiddenPowerUps lists the power-ups still hidden, in row-major order
+func (gm *GameMap) HiddenPowerUps() []HiddenPowerUp {
+	list := make([]HiddenPowerUp, 0, len(gm.hidden))
+	for pos, powerUpType := range gm.hidden {
+		list = append(list, HiddenPowerUp{Type: powerUpType, Position: pos})
 	}
-	gm.generateMap()
-	return gm
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Position.Y != list[j].Position.Y {
+			return list[i].Position.Y < list[j].Position.Y
+		}
+		return list[i].Position.X < list[j].Position.X
+	})
+	return list
 }
 
 // Place player if tile is empty
@@ -79,7 +143,7 @@ func (gm *GameMap) PlacePlayer(player *Player, x, y int) bool {
 
 // --- Helpers below ---
 func (gm *GameMap) IsValidPosition(pos Position) bool {
-	return pos.X >= 0 && pos.X < MapWidth && pos.Y >= 0 && pos.Y < MapHeight
+	return pos.X >= 0 && pos.X < gm.Width && pos.Y >= 0 && pos.Y < gm.Height
 }
 
 func (gm *GameMap) IsWall(pos Position) bool {
@@ -8,19 +8,38 @@ type Position struct {
 }
 
 type Player struct {
-	ID          string   `json:"id"`
-	Nickname    string   `json:"nickname"`
-	Position    Position `json:"position"`
-	Lives       int      `json:"lives"`
-	Speed       float64  `json:"speed"`
-	MaxBombs    int      `json:"maxBombs"`
-	BombPower   int      `json:"bombPower"`
-	ActiveBombs int      `json:"activeBombs"`
-	Direction   string   `json:"direction"`
-	Frame       int      `json:"frame"`
-	Number      int      `json:"number"` // <-- add this
-	IsConnected bool     `json:"-"` // Server-side flag
-	DisconnectedAt time.Time `json:"-"` // Server-side timestamp
+	ID                string      `json:"id"`
+	Nickname          string      `json:"nickname"`
+	Position          Position    `json:"position"`
+	Lives             int         `json:"lives"`
+	Speed             float64     `json:"speed"`
+	MaxBombs          int         `json:"maxBombs"`
+	BombPower         int         `json:"bombPower"`
+	ActiveBombs       int         `json:"activeBombs"`
+	Direction         string      `json:"direction"`
+	Frame             int         `json:"frame"`
+	Number            int         `json:"number"`          // <-- add this
+	Team              int         `json:"team,omitempty"`  // 1 to Settings.Teams in team mode, 0 in free-for-all
+	Bot               string      `json:"bot,omitempty"`   // Difficulty of a server-side bot, empty for humans
+	Invulnerable      bool        `json:"invulnerable"`    // True while recovering from a hit, clients blink the sprite
+	Eliminated        bool        `json:"eliminated"`      // Out of lives; frozen and spectating
+	CanKick           bool        `json:"canKick"`         // Walking into a bomb kicks it
+	CanPunch          bool        `json:"canPunch"`        // The punch action throws the bomb in front
+	RemoteControl     bool        `json:"remoteControl"`   // New bombs wait for the detonate action
+	PierceBombs       bool        `json:"pierceBombs"`     // New bombs blast through destructible blocks
+	Curse             string      `json:"curse,omitempty"` // Active skull curse, see CurseTypes
+	Stats             PlayerStats `json:"stats"`
+	LastInputSeq      uint64      `json:"lastInputSeq"` // Sequence number of the last action applied, for client reconciliation
+	SessionToken      string      `json:"-"`            // Secret proving a connection acts for this player
+	IsConnected       bool        `json:"-"`            // Server-side flag
+	DisconnectedAt    time.Time   `json:"-"`            // Server-side timestamp
+	LastMoveAt        time.Time   `json:"-"`            // When the player last took a step
+	PendingMove       *Position   `json:"-"`            // Direction of a move received during the cooldown
+	PendingMoveSeq    uint64      `json:"-"`            // Sequence number of the input behind PendingMove
+	SpawnPosition     Position    `json:"-"`            // Start slot, used when respawning
+	InvulnerableUntil time.Time   `json:"-"`            // End of the post-hit protection
+	EliminatedAt      time.Time   `json:"-"`            // When the player lost their last life
+	CurseUntil        time.Time   `json:"-"`            // When the curse wears off
 }
 
 // NewPlayer creates a new player with default values
@@ -29,11 +48,13 @@ func NewPlayer(id, nickname string, startX, startY int) *Player {
 		ID:             id,
 		Nickname:       nickname,
 		Position:       Position{X: startX, Y: startY},
+		SpawnPosition:  Position{X: startX, Y: startY},
 		Lives:          PLAYER_MAX_LIVES, // Use constant if available, otherwise 3
 		Speed:          1.0,
 		MaxBombs:       1,
 		BombPower:      1,
 		ActiveBombs:    0,
+		SessionToken:   GenerateToken(),
 		IsConnected:    true,
 		DisconnectedAt: time.Time{},
 	}
@@ -65,12 +86,31 @@ func (p *Player) Move(dx, dy int, gameMap *GameMap) bool {
 	return false
 }
 
-func (p *Player) PlaceBomb(gameMap *GameMap) *Bomb {
+// MoveCooldown is the minimum time between two steps. It shrinks as Speed grows.
+func (p *Player) MoveCooldown(baseInterval time.Duration) time.Duration {
+	speed := p.Speed
+	if p.Curse == CurseSlow {
+		speed = SlowCurseSpeed
+	}
+	if speed <= 0 {
+		return baseInterval
+	}
+	return time.Duration(float64(baseInterval) / speed)
+}
+
+// CanMove reports whether the player's movement cooldown has ended
+func (p *Player) CanMove(now time.Time, baseInterval time.Duration) bool {
+	return now.Sub(p.LastMoveAt) >= p.MoveCooldown(baseInterval)
+}
+
+func (p *Player) PlaceBomb(gameMap *GameMap, now time.Time) *Bomb {
 	if p.ActiveBombs >= p.MaxBombs {
 		return nil
 	}
 
-	bomb := NewBomb(p.Position, p.BombPower, p.ID)
+	bomb := NewBomb(p.Position, p.BombPower, p.ID, now)
+	bomb.Remote = p.RemoteControl
+	bomb.Pierce = p.PierceBombs
 	p.ActiveBombs++
 	return bomb
 }
@@ -86,28 +126,82 @@ func (p *Player) Hit() bool {
 	return p.Lives <= 0 // Returns true if player is eliminated
 }
 
-func (p *Player) ApplyPowerUp(powerUp PowerUp) {
+// IsActive reports whether the player is still in play and may move, bomb and pick up power-ups
+func (p *Player) IsActive() bool {
+	return !p.Eliminated && p.Lives > 0
+}
+
+// IsInvulnerable reports whether the player is still protected after a hit
+func (p *Player) IsInvulnerable(now time.Time) bool {
+	return now.Before(p.InvulnerableUntil)
+}
+
+// Respawn moves the player back to their start slot
+func (p *Player) Respawn() {
+	p.Position = p.SpawnPosition
+	p.PendingMove = nil
+}
+
+// ApplyPowerUp grows the player's stats or grants an ability, within the rules' caps
+func (p *Player) ApplyPowerUp(powerUp PowerUp, rules Rules) {
 	switch powerUp.Type {
 	case PowerUpSpeed:
-		// More meaningful speed increments
-		// First power-up gives +0.3, second +0.4, etc.
-		// This creates a more noticeable but not overwhelming effect
-		if p.Speed < 1.0 {
-			// First speed upgrade
-			p.Speed = 1.0
-		} else if p.Speed < 1.5 {
-			// Second speed upgrade
-			p.Speed = 1.5
-		} else if p.Speed < 2.0 {
-			// Third speed upgrade
-			p.Speed = 2.0
-		} else {
-			// Additional upgrades cap at 2.5
-			p.Speed = 2.5
+		p.Speed += rules.Speed.Increment
+		if p.Speed > rules.Speed.Max {
+			p.Speed = rules.Speed.Max
 		}
 	case PowerUpBomb:
-		p.MaxBombs++
+		p.MaxBombs = addStat(p.MaxBombs, rules.Bombs)
 	case PowerUpFlame:
-		p.BombPower++
+		p.BombPower = addStat(p.BombPower, rules.Power)
+	case PowerUpKick:
+		p.CanKick = true
+	case PowerUpPunch:
+		p.CanPunch = true
+	case PowerUpRemote:
+		p.RemoteControl = true
+	case PowerUpPierce:
+		p.PierceBombs = true
+	case PowerUpFullFire:
+		p.BombPower = rules.Power.Max
+	}
+	// Skulls are handled by Game.collectPowerUps, which draws the curse from the game's random source
+}
+
+// ResetStats puts the player's stats back to the rules' starting values and takes
+// away their abilities and curse. Lives are left alone.
+func (p *Player) ResetStats(rules Rules) {
+	p.Speed = rules.Speed.Start
+	p.MaxBombs = rules.Bombs.Start
+	p.BombPower = rules.Power.Start
+	p.CanKick = false
+	p.CanPunch = false
+	p.RemoteControl = false
+	p.PierceBombs = false
+	p.SetCurse("", time.Time{})
+}
+
+// SetCurse curses the player until the given time; an empty curse lifts it
+func (p *Player) SetCurse(curse string, until time.Time) {
+	p.Curse = curse
+	p.CurseUntil = until
+	if curse == "" {
+		p.CurseUntil = time.Time{}
+	}
+}
+
+// directionDeltas maps Player.Direction to a step
+var directionDeltas = map[string]Position{
+	"up":    {X: 0, Y: -1},
+	"down":  {X: 0, Y: 1},
+	"left":  {X: -1, Y: 0},
+	"right": {X: 1, Y: 0},
+}
+
+// Facing returns the step in the direction the player last moved, down if they never moved
+func (p *Player) Facing() Position {
+	if delta, ok := directionDeltas[p.Direction]; ok {
+		return delta
 	}
+	return directionDeltas["down"]
 }
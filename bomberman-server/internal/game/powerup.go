@@ -1,27 +1,133 @@
 package game
 
-import "math/rand"
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
 
 // PowerUp represents a power-up item in the game.
 type PowerUp struct {
 	ID       string
 	Type     string
 	Position Position
+
+	// When it appeared, for POWERUP_SPAWN_GRACE. Zero counts as long ago; only
+	// explosions spawn power-ups, and they do so while the match is running, so
+	// match snapshots never hold one still inside its grace window.
+	SpawnedAt time.Time `json:"-"`
+}
+
+const (
+	PowerUpSpeed    = "speed"    // Increases movement speed
+	PowerUpBomb     = "bomb"     // Increases the amount of bombs dropped at a time by 1
+	PowerUpFlame    = "flame"    // Increases explosion range from the bomb in four directions by 1 block
+	PowerUpKick     = "kick"     // Walking into a bomb kicks it, and it slides until something stops it
+	PowerUpPunch    = "punch"    // The punch action throws the bomb in front of the player over obstacles
+	PowerUpRemote   = "remote"   // Bombs wait for the detonate action instead of a fuse
+	PowerUpPierce   = "pierce"   // Explosions go through destructible blocks instead of stopping at the first
+	PowerUpFullFire = "fullfire" // Explosion range goes straight to Rules.Power.Max
+	PowerUpSkull    = "skull"    // Curses the player for CurseDuration; the curse passes on by touch
+)
+
+// PowerUpTypes lists every power-up type. The order is fixed: hidden power-ups and
+// random drops are drawn in this order, so changing it changes seeded games.
+var PowerUpTypes = []string{
+	PowerUpSpeed, PowerUpBomb, PowerUpFlame,
+	PowerUpKick, PowerUpPunch, PowerUpRemote, PowerUpPierce, PowerUpFullFire, PowerUpSkull,
 }
 
+// Curses a skull can give
 const (
-	PowerUpSpeed = "speed" // Increases movement speed
-	PowerUpBomb  = "bomb"  // Increases the amount of bombs dropped at a time by 1
-	PowerUpFlame = "flame" // Increases explosion range from the bomb in four directions by 1 block
+	CurseReverse  = "reverse"  // Movement directions are swapped
+	CurseDiarrhea = "diarrhea" // A bomb is dropped whenever possible
+	CurseSlow     = "slow"     // Moves at SlowCurseSpeed whatever speed power-ups were collected
 )
 
-// SpawnPowerUp spawns a power-up at a given position.
-func SpawnPowerUp(position Position) PowerUp {
-	powerUpTypes := []string{PowerUpSpeed, PowerUpBomb, PowerUpFlame}
-	randomType := powerUpTypes[rand.Intn(len(powerUpTypes))]
+// CurseTypes lists the curses in the order they are drawn
+var CurseTypes = []string{CurseReverse, CurseDiarrhea, CurseSlow}
+
+const (
+	FullFirePower     = MaxMapSize            // Highest blast range of the built-in rules, enough to cross any map
+	PunchDistance     = 3                     // Tiles a punched bomb flies before trying to land
+	BombSlideInterval = 50 * time.Millisecond // Time a kicked bomb takes to slide one tile
+	CurseDuration     = 10 * time.Second      // How long a skull's curse lasts
+	SlowCurseSpeed    = 0.5                   // Speed of a player with CurseSlow
+)
+
+// How power-ups appear on the map
+const (
+	PowerUpModeRandom = "random" // Each destroyed block drops a random power-up with Settings.PowerUpSpawnRate
+	PowerUpModeHidden = "hidden" // Settings.HiddenPowerUps are placed under blocks when the map is built
+)
+
+// ValidatePowerUpMode checks a power-up mode and, for the hidden mode, the number of
+// power-ups of each type.
+func ValidatePowerUpMode(mode string, hidden map[string]int) error {
+	switch mode {
+	case PowerUpModeRandom:
+		return nil
+	case PowerUpModeHidden:
+	default:
+		return fmt.Errorf("unknown power-up mode %q (want %q or %q)", mode, PowerUpModeRandom, PowerUpModeHidden)
+	}
+
+	for powerUpType, count := range hidden {
+		if !isPowerUpType(powerUpType) {
+			return fmt.Errorf("unknown power-up type %q", powerUpType)
+		}
+		if count < 0 {
+			return fmt.Errorf("hidden %s power-ups must not be negative, got %d", powerUpType, count)
+		}
+	}
+	return nil
+}
+
+// isPowerUpType reports whether a power-up type exists
+func isPowerUpType(powerUpType string) bool {
+	for _, t := range PowerUpTypes {
+		if t == powerUpType {
+			return true
+		}
+	}
+	return false
+}
+
+// SpawnPowerUp spawns a power-up at a given position, its type drawn from rng with the
+// given relative weights. It returns false if every weight is zero.
+func SpawnPowerUp(position Position, weights map[string]int, rng *rand.Rand) (PowerUp, bool) {
+	total := 0
+	for _, t := range PowerUpTypes {
+		total += weights[t]
+	}
+	if total <= 0 {
+		return PowerUp{}, false
+	}
+
+	roll := rng.Intn(total)
+	for _, t := range PowerUpTypes {
+		if roll < weights[t] {
+			return NewPowerUp(t, position), true
+		}
+		roll -= weights[t]
+	}
+	return PowerUp{}, false // Unreachable: the rolls add up to total
+}
+
+// NewPowerUp creates a power-up of the given type at a position. It gets its ID when
+// the game places it.
+func NewPowerUp(powerUpType string, position Position) PowerUp {
 	return PowerUp{
-		ID:       GenerateUUID(),
-		Type:     randomType,
+		Type:     powerUpType,
 		Position: position,
 	}
 }
+
+// placePowerUp puts a power-up on the map. IDs count up within the game, so sorting
+// by ID gives the same order on every run and in replays. Callers must hold g.Mutex.
+func (g *Game) placePowerUp(powerUp PowerUp, now time.Time) {
+	g.powerUpCount++
+	powerUp.ID = fmt.Sprintf("powerup-%d", g.powerUpCount)
+	powerUp.SpawnedAt = now
+	g.PowerUps[powerUp.ID] = powerUp
+}
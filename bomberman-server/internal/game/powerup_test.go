@@ -0,0 +1,57 @@
+package game
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// drops spawns n power-ups from a source seeded with seed and returns their types
+func drops(seed int64, weights map[string]int, n int) []string {
+	rng := rand.New(rand.NewSource(seed))
+	types := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		powerUp, ok := SpawnPowerUp(Position{X: i, Y: 1}, weights, rng)
+		if !ok {
+			types = append(types, "")
+			continue
+		}
+		types = append(types, powerUp.Type)
+	}
+	return types
+}
+
+func TestSpawnPowerUpIsReproducible(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+	}{
+		{"classic", ClassicRules().DropWeights},
+		{"chaos", ChaosRules().DropWeights},
+		{"skulls only", map[string]int{PowerUpSkull: 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, seed := range []int64{1, 42, -9} {
+				first := drops(seed, tc.weights, 100)
+				if second := drops(seed, tc.weights, 100); !reflect.DeepEqual(first, second) {
+					t.Errorf("seed %d dropped %v, then %v", seed, first, second)
+				}
+				for i, kind := range first {
+					if tc.weights[kind] <= 0 {
+						t.Fatalf("seed %d drop %d is %q, which has no weight", seed, i, kind)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSpawnPowerUpWithoutWeights(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, weights := range []map[string]int{nil, {}, {PowerUpBomb: 0}} {
+		if powerUp, ok := SpawnPowerUp(Position{X: 1, Y: 1}, weights, rng); ok {
+			t.Errorf("weights %v dropped %v", weights, powerUp)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package game
+
+import (
+	"math/rand"
+	"time"
+)
+
+// newSeed picks a seed for a round whose rules do not fix one
+func newSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// seedRound picks the seed of a new round: Settings.Seed if set, a fresh one
+// otherwise. Callers must hold g.Mutex.
+func (g *Game) seedRound() {
+	g.Seed = g.Settings.Seed
+	if g.Seed == 0 {
+		g.Seed = newSeed()
+	}
+	g.rng = rand.New(rand.NewSource(g.Seed))
+}
@@ -0,0 +1,379 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Connection changes are recorded alongside player actions because they affect the
+// match (a player who stays away too long is eliminated). They are never queued as input.
+const (
+	ActionDisconnect = "disconnect"
+	ActionReconnect  = "reconnect"
+)
+
+// Recording is everything needed to re-simulate a match: the state at the tick it
+// started and every action applied after that, in order. Replaying the actions
+// through Update from the snapshot reproduces the match exactly.
+type Recording struct {
+	ID        string           `json:"id"`
+	GameID    string           `json:"gameId"`
+	StartedAt time.Time        `json:"startedAt"` // Wall clock, for display only
+	Seed      int64            `json:"seed"`      // Seed of the match's random source
+	Settings  Settings         `json:"settings"`
+	Snapshot  MatchSnapshot    `json:"snapshot"`
+	Actions   []RecordedAction `json:"actions"`
+	Ticks     uint64           `json:"ticks"`            // Length of the match in ticks
+	Result    *MatchResult     `json:"result,omitempty"` // Nil if the match was cut short by a reset
+}
+
+// Duration returns the length of the recorded match
+func (r *Recording) Duration() time.Duration {
+	return time.Duration(r.Ticks) * r.Settings.TickDuration
+}
+
+// RecordedAction is one action applied during a match. It is applied before tick
+// Tick (counted from the start of the match) runs.
+type RecordedAction struct {
+	Tick     uint64 `json:"tick"`
+	TimeMs   int64  `json:"timeMs"` // Tick as milliseconds since the start of the match
+	PlayerID string `json:"playerId"`
+	Seq      uint64 `json:"seq,omitempty"`      // Client sequence number of a player action
+	Action   string `json:"action"`             // A player action, ActionDisconnect or ActionReconnect
+	Nickname string `json:"nickname,omitempty"` // Name used to reconnect
+}
+
+// MatchSnapshot is the game state on the tick the match started
+type MatchSnapshot struct {
+	Map          string             `json:"map"`
+	Spawns       []Position         `json:"spawns"` // Player slots of the map, in order
+	Blocks       [][]BlockType      `json:"blocks"`
+	Hidden       []HiddenPowerUp    `json:"hiddenPowerUps,omitempty"` // Power-ups still under blocks
+	Players      []PlayerSnapshot   `json:"players"`                  // In slot order
+	Bombs        []BombSnapshot     `json:"bombs"`                    // Bombs placed during the countdown, in placement order
+	PowerUps     map[string]PowerUp `json:"powerUps"`
+	BombCount    uint64             `json:"bombCount"`
+	PowerUpCount uint64             `json:"powerUpCount"`
+}
+
+// PlayerSnapshot is a player together with the server-side fields the state broadcast
+// leaves out. Times are offsets from the start of the match; nil means unset.
+// The session token is never recorded.
+type PlayerSnapshot struct {
+	Player
+	Connected               bool           `json:"connected"`
+	Spawn                   Position       `json:"spawn"`
+	Pending                 *Position      `json:"pendingMove,omitempty"`
+	PendingSeq              uint64         `json:"pendingMoveSeq,omitempty"`
+	DisconnectedOffset      *time.Duration `json:"disconnectedAt,omitempty"`
+	LastMoveOffset          *time.Duration `json:"lastMoveAt,omitempty"`
+	InvulnerableUntilOffset *time.Duration `json:"invulnerableUntil,omitempty"`
+	EliminatedOffset        *time.Duration `json:"eliminatedAt,omitempty"`
+	CurseUntilOffset        *time.Duration `json:"curseUntil,omitempty"`
+}
+
+// BombSnapshot is a bomb with its placement time as an offset from the start of the match
+type BombSnapshot struct {
+	ID          string         `json:"id"`
+	Position    Position       `json:"position"`
+	Power       int            `json:"power"`
+	PlayerID    string         `json:"playerId"`
+	PlacedAt    time.Duration  `json:"placedAt"`
+	Timer       time.Duration  `json:"timer"`
+	Seq         uint64         `json:"seq"`
+	OwnerOnTile bool           `json:"ownerOnTile"`
+	Remote      bool           `json:"remote,omitempty"`
+	Pierce      bool           `json:"pierce,omitempty"`
+	Detonated   bool           `json:"detonated,omitempty"`
+	Sliding     Position       `json:"sliding"`
+	LastSlideAt *time.Duration `json:"lastSlideAt,omitempty"`
+}
+
+// offsetOf converts a simulated time to an offset from start. The zero time becomes nil.
+func offsetOf(t, start time.Time) *time.Duration {
+	if t.IsZero() {
+		return nil
+	}
+	d := t.Sub(start)
+	return &d
+}
+
+// timeAt is the inverse of offsetOf
+func timeAt(offset *time.Duration, start time.Time) time.Time {
+	if offset == nil {
+		return time.Time{}
+	}
+	return start.Add(*offset)
+}
+
+// startRecording rewinds the random source to the round's seed and snapshots the
+// match that starts on this tick. Callers must hold g.Mutex.
+func (g *Game) startRecording(now time.Time) {
+	g.rng = rand.New(rand.NewSource(g.Seed)) // Drops never depend on what happened in the lobby
+
+	snapshot := MatchSnapshot{
+		Map:          g.Map.Name,
+		Spawns:       append([]Position(nil), g.Map.Spawns...),
+		Blocks:       make([][]BlockType, len(g.Map.Blocks)),
+		Hidden:       g.Map.HiddenPowerUps(),
+		Players:      make([]PlayerSnapshot, 0, len(g.Players)),
+		Bombs:        make([]BombSnapshot, 0, len(g.Bombs)),
+		PowerUps:     make(map[string]PowerUp, len(g.PowerUps)),
+		BombCount:    g.bombCount,
+		PowerUpCount: g.powerUpCount,
+	}
+	for y, row := range g.Map.Blocks {
+		snapshot.Blocks[y] = append([]BlockType(nil), row...)
+	}
+	for _, p := range g.PlayersInSlotOrder() {
+		player := *p
+		player.SessionToken = ""
+		var pending *Position
+		if p.PendingMove != nil {
+			move := *p.PendingMove
+			pending = &move
+		}
+		snapshot.Players = append(snapshot.Players, PlayerSnapshot{
+			Player:                  player,
+			Connected:               p.IsConnected,
+			Spawn:                   p.SpawnPosition,
+			Pending:                 pending,
+			PendingSeq:              p.PendingMoveSeq,
+			DisconnectedOffset:      offsetOf(p.DisconnectedAt, now),
+			LastMoveOffset:          offsetOf(p.LastMoveAt, now),
+			InvulnerableUntilOffset: offsetOf(p.InvulnerableUntil, now),
+			EliminatedOffset:        offsetOf(p.EliminatedAt, now),
+			CurseUntilOffset:        offsetOf(p.CurseUntil, now),
+		})
+	}
+	bombs := make([]*Bomb, 0, len(g.Bombs))
+	for _, bomb := range g.Bombs {
+		bombs = append(bombs, bomb)
+	}
+	sortBombs(bombs)
+	for _, bomb := range bombs {
+		snapshot.Bombs = append(snapshot.Bombs, BombSnapshot{
+			ID:          bomb.ID,
+			Position:    bomb.Position,
+			Power:       bomb.Power,
+			PlayerID:    bomb.PlayerID,
+			PlacedAt:    bomb.PlacedAt.Sub(now),
+			Timer:       bomb.Timer,
+			Seq:         bomb.Seq,
+			OwnerOnTile: bomb.OwnerOnTile,
+			Remote:      bomb.Remote,
+			Pierce:      bomb.Pierce,
+			Detonated:   bomb.Detonated,
+			Sliding:     bomb.Sliding,
+			LastSlideAt: offsetOf(bomb.LastSlideAt, now),
+		})
+	}
+	for id, powerUp := range g.PowerUps {
+		snapshot.PowerUps[id] = powerUp
+	}
+
+	g.recording = &Recording{
+		ID:        GenerateUUID(),
+		GameID:    g.ID,
+		StartedAt: time.Now(),
+		Seed:      g.Seed,
+		Settings:  g.Settings,
+		Snapshot:  snapshot,
+		Actions:   make([]RecordedAction, 0),
+	}
+	g.recordStart = g.Tick
+}
+
+// record appends an action applied before the given tick. Callers must hold g.Mutex.
+func (g *Game) record(tick uint64, playerID string, seq uint64, action, nickname string) {
+	if g.recording == nil {
+		return
+	}
+	offset := tick - g.recordStart
+	g.recording.Actions = append(g.recording.Actions, RecordedAction{
+		Tick:     offset,
+		TimeMs:   (time.Duration(offset) * g.Settings.TickDuration).Milliseconds(),
+		PlayerID: playerID,
+		Seq:      seq,
+		Action:   action,
+		Nickname: nickname,
+	})
+}
+
+// recordInput records an input applied by the current tick. Callers must hold g.Mutex.
+func (g *Game) recordInput(input Input) {
+	g.record(g.Tick, input.PlayerID, input.Seq, input.Action, "")
+}
+
+// recordBetweenTicks records something that happened outside Update; a replay applies
+// it right before the next tick. Callers must hold g.Mutex.
+func (g *Game) recordBetweenTicks(playerID, action, nickname string) {
+	g.record(g.Tick+1, playerID, 0, action, nickname)
+}
+
+// stopRecording ends the current recording, if any, and hands it to OnRecording.
+// Callers must hold g.Mutex.
+func (g *Game) stopRecording() {
+	rec := g.recording
+	if rec == nil {
+		return
+	}
+	g.recording = nil
+	rec.Ticks = g.Tick - g.recordStart
+	rec.Result = g.Result // Cleared when the match started, so nil unless it finished
+	if g.OnRecording != nil {
+		g.OnRecording(rec)
+	}
+}
+
+// StopRecording saves the match in progress, if any, without a result. It is used when
+// the game is torn down mid-match.
+func (g *Game) StopRecording() {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.stopRecording()
+}
+
+// NewReplayGame creates a game positioned at the start of a recorded match. Advance it
+// with ApplyRecordedAction and Update; it is never driven by a Loop.
+func NewReplayGame(rec *Recording) (*Game, error) {
+	g, err := NewGame(rec.Settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Restore(rec); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Restore puts the game back to the start of a recorded match, discarding its current state
+func (g *Game) Restore(rec *Recording) error {
+	if rec.Settings.TickDuration <= 0 {
+		return errors.New("recording has no tick duration")
+	}
+	height := len(rec.Snapshot.Blocks)
+	if height < MinMapSize || height > MaxMapSize {
+		return fmt.Errorf("recording map has %d rows, want %d to %d", height, MinMapSize, MaxMapSize)
+	}
+	width := len(rec.Snapshot.Blocks[0])
+	if width < MinMapSize || width > MaxMapSize {
+		return fmt.Errorf("recording map has %d columns, want %d to %d", width, MinMapSize, MaxMapSize)
+	}
+	for y, row := range rec.Snapshot.Blocks {
+		if len(row) != width {
+			return fmt.Errorf("recording map row %d has %d tiles, want %d like row 0", y, len(row), width)
+		}
+	}
+	if len(rec.Snapshot.Spawns) < MinSpawns {
+		return fmt.Errorf("recording map has %d spawn points, want at least %d", len(rec.Snapshot.Spawns), MinSpawns)
+	}
+
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	g.ID = rec.GameID
+	g.Settings = rec.Settings
+	g.Tick = 0
+	start := g.now()
+
+	g.Map = &GameMap{
+		Blocks: make([][]BlockType, height),
+		Width:  width,
+		Height: height,
+		hidden: make(map[Position]string, len(rec.Snapshot.Hidden)),
+	}
+	for y, row := range rec.Snapshot.Blocks {
+		g.Map.Blocks[y] = append([]BlockType(nil), row...)
+	}
+	for _, powerUp := range rec.Snapshot.Hidden {
+		g.Map.hidden[powerUp.Position] = powerUp.Type
+	}
+	g.Map.Name = rec.Snapshot.Map
+	g.Map.Spawns = append([]Position(nil), rec.Snapshot.Spawns...)
+
+	g.Players = make(map[string]*Player, len(rec.Snapshot.Players))
+	for _, s := range rec.Snapshot.Players {
+		player := s.Player
+		player.IsConnected = s.Connected
+		player.SpawnPosition = s.Spawn
+		player.PendingMove = nil
+		if s.Pending != nil {
+			move := *s.Pending
+			player.PendingMove = &move
+		}
+		player.PendingMoveSeq = s.PendingSeq
+		player.DisconnectedAt = timeAt(s.DisconnectedOffset, start)
+		player.LastMoveAt = timeAt(s.LastMoveOffset, start)
+		player.InvulnerableUntil = timeAt(s.InvulnerableUntilOffset, start)
+		player.EliminatedAt = timeAt(s.EliminatedOffset, start)
+		player.CurseUntil = timeAt(s.CurseUntilOffset, start)
+		g.Players[player.ID] = &player
+	}
+
+	g.Bombs = make(map[string]*Bomb, len(rec.Snapshot.Bombs))
+	for _, s := range rec.Snapshot.Bombs {
+		g.Bombs[s.ID] = &Bomb{
+			ID:          s.ID,
+			Position:    s.Position,
+			Power:       s.Power,
+			PlayerID:    s.PlayerID,
+			PlacedAt:    start.Add(s.PlacedAt),
+			Timer:       s.Timer,
+			Seq:         s.Seq,
+			OwnerOnTile: s.OwnerOnTile,
+			Remote:      s.Remote,
+			Pierce:      s.Pierce,
+			Detonated:   s.Detonated,
+			Sliding:     s.Sliding,
+			LastSlideAt: timeAt(s.LastSlideAt, start),
+		}
+	}
+	g.bombCount = rec.Snapshot.BombCount
+	g.powerUpCount = rec.Snapshot.PowerUpCount
+
+	g.PowerUps = make(map[string]PowerUp, len(rec.Snapshot.PowerUps))
+	for id, powerUp := range rec.Snapshot.PowerUps {
+		g.PowerUps[id] = powerUp
+	}
+
+	g.Seed = rec.Seed
+	g.rng = rand.New(rand.NewSource(rec.Seed))
+
+	g.State = GameRunning
+	g.StartTime = start
+	g.CountdownTimer = time.Time{}
+	g.WaitingTimer = time.Time{}
+	g.ResetTimer = time.Time{}
+	g.InitialPlayerCount = len(g.Players)
+	g.Result = nil
+	g.clearSuddenDeath()
+	g.Explosions = make([]TimedExplosion, 0)
+	g.events = nil
+	g.recording = nil
+	g.Map.Players = g.PlayersInSlotOrder()
+
+	g.inputMutex.Lock()
+	g.inputs = nil
+	g.queuedInputs = nil
+	g.inputMutex.Unlock()
+	return nil
+}
+
+// ApplyRecordedAction feeds one recorded action back into the game. Player actions
+// are queued for the next Update with their original sequence number, so the
+// acknowledged sequence numbers match the live match too.
+func (g *Game) ApplyRecordedAction(action RecordedAction) error {
+	switch action.Action {
+	case ActionDisconnect:
+		g.HandlePlayerDisconnect(action.PlayerID)
+		return nil
+	case ActionReconnect:
+		_, err := g.AddPlayer(action.PlayerID, action.Nickname)
+		return err
+	default:
+		return g.QueueInput(Input{PlayerID: action.PlayerID, Seq: action.Seq, Action: action.Action})
+	}
+}
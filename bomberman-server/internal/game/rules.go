@@ -0,0 +1,165 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Names of the built-in rule sets
+const (
+	RulesClassic     = "classic"
+	RulesChaos       = "chaos"
+	RulesCompetitive = "competitive"
+	RulesCustom      = "custom" // Name given to rule sets without one that are not built in
+)
+
+// StatRule is how a player stat starts, how much a power-up adds and where it stops
+type StatRule struct {
+	Start     int `json:"start" yaml:"start"`
+	Increment int `json:"increment" yaml:"increment"`
+	Max       int `json:"max" yaml:"max"`
+}
+
+// SpeedRule is StatRule for the movement speed multiplier
+type SpeedRule struct {
+	Start     float64 `json:"start" yaml:"start"`
+	Increment float64 `json:"increment" yaml:"increment"`
+	Max       float64 `json:"max" yaml:"max"`
+}
+
+// Rules decide how players start and grow over a match.
+type Rules struct {
+	Name        string         `json:"name" yaml:"name"`
+	Lives       int            `json:"lives" yaml:"lives"`               // Lives every player starts with
+	Speed       SpeedRule      `json:"speed" yaml:"speed"`               // Movement speed, see Player.MoveCooldown
+	Bombs       StatRule       `json:"bombs" yaml:"bombs"`               // Bombs a player can have out at once
+	Power       StatRule       `json:"power" yaml:"power"`               // Blast range in tiles; also the full fire range
+	DropWeights map[string]int `json:"dropWeights" yaml:"drop_weights"`  // Relative chance of each type in random mode; missing types never drop
+	LoseOnDeath bool           `json:"loseOnDeath" yaml:"lose_on_death"` // Losing a life resets stats and abilities to the start
+}
+
+// presets holds the built-in rule sets by name
+var presets = map[string]func() Rules{
+	RulesClassic:     ClassicRules,
+	RulesChaos:       ChaosRules,
+	RulesCompetitive: CompetitiveRules,
+}
+
+// ClassicRules are the standard rules: modest caps and mostly plain power-ups.
+func ClassicRules() Rules {
+	return Rules{
+		Name:  RulesClassic,
+		Lives: PLAYER_MAX_LIVES,
+		Speed: SpeedRule{Start: 1.0, Increment: 0.5, Max: 2.5},
+		Bombs: StatRule{Start: 1, Increment: 1, Max: 8},
+		Power: StatRule{Start: 1, Increment: 1, Max: FullFirePower},
+		DropWeights: map[string]int{
+			PowerUpSpeed:    4,
+			PowerUpBomb:     4,
+			PowerUpFlame:    4,
+			PowerUpKick:     2,
+			PowerUpPunch:    1,
+			PowerUpRemote:   1,
+			PowerUpPierce:   1,
+			PowerUpFullFire: 1,
+			PowerUpSkull:    2,
+		},
+	}
+}
+
+// ChaosRules start players strong, let them grow fast and drop special items and skulls often.
+func ChaosRules() Rules {
+	return Rules{
+		Name:  RulesChaos,
+		Lives: PLAYER_MAX_LIVES,
+		Speed: SpeedRule{Start: 1.0, Increment: 0.5, Max: 3.0},
+		Bombs: StatRule{Start: 2, Increment: 2, Max: 12},
+		Power: StatRule{Start: 2, Increment: 2, Max: FullFirePower},
+		DropWeights: map[string]int{
+			PowerUpSpeed:    2,
+			PowerUpBomb:     2,
+			PowerUpFlame:    2,
+			PowerUpKick:     3,
+			PowerUpPunch:    3,
+			PowerUpRemote:   3,
+			PowerUpPierce:   3,
+			PowerUpFullFire: 2,
+			PowerUpSkull:    4,
+		},
+	}
+}
+
+// CompetitiveRules keep caps low, leave out items that decide matches on luck and
+// take power-ups away from players who lose a life.
+func CompetitiveRules() Rules {
+	return Rules{
+		Name:  RulesCompetitive,
+		Lives: PLAYER_MAX_LIVES,
+		Speed: SpeedRule{Start: 1.0, Increment: 0.25, Max: 2.0},
+		Bombs: StatRule{Start: 1, Increment: 1, Max: 5},
+		Power: StatRule{Start: 1, Increment: 1, Max: 6},
+		DropWeights: map[string]int{
+			PowerUpSpeed:  3,
+			PowerUpBomb:   4,
+			PowerUpFlame:  4,
+			PowerUpKick:   2,
+			PowerUpPunch:  1,
+			PowerUpPierce: 1,
+		},
+		LoseOnDeath: true,
+	}
+}
+
+// RulesByName returns a built-in rule set
+func RulesByName(name string) (Rules, error) {
+	preset, ok := presets[name]
+	if !ok {
+		return Rules{}, fmt.Errorf("unknown rules %q (want one of %s)", name, strings.Join(RuleNames(), ", "))
+	}
+	return preset(), nil
+}
+
+// RuleNames lists the built-in rule sets, sorted
+func RuleNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that every stat can start and grow sensibly and that drop weights
+// only name known power-ups.
+func (r Rules) Validate() error {
+	if r.Lives < 1 {
+		return fmt.Errorf("lives must be at least 1, got %d", r.Lives)
+	}
+	if r.Speed.Start <= 0 || r.Speed.Increment < 0 || r.Speed.Max < r.Speed.Start {
+		return fmt.Errorf("speed needs start > 0, increment >= 0 and max >= start, got %+v", r.Speed)
+	}
+	for name, stat := range map[string]StatRule{"bombs": r.Bombs, "power": r.Power} {
+		if stat.Start < 1 || stat.Increment < 0 || stat.Max < stat.Start {
+			return fmt.Errorf("%s needs start >= 1, increment >= 0 and max >= start, got %+v", name, stat)
+		}
+	}
+	for powerUpType, weight := range r.DropWeights {
+		if !isPowerUpType(powerUpType) {
+			return fmt.Errorf("unknown power-up type %q in drop weights", powerUpType)
+		}
+		if weight < 0 {
+			return fmt.Errorf("drop weight of %s must not be negative, got %d", powerUpType, weight)
+		}
+	}
+	return nil
+}
+
+// addStat adds a rule's increment to a stat, stopping at the cap
+func addStat(value int, rule StatRule) int {
+	value += rule.Increment
+	if value > rule.Max {
+		value = rule.Max
+	}
+	return value
+}
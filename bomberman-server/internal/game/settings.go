@@ -0,0 +1,52 @@
+package game
+
+import "time"
+
+// Settings holds the tunable rules of a single game.
+type Settings struct {
+	MaxPlayers          int               `json:"maxPlayers"`                    // Lobby size, up to MAX_PLAYERS and the map's spawns; the game starts immediately once it is full
+	PowerUpMode         string            `json:"powerUpMode"`                   // PowerUpModeRandom or PowerUpModeHidden
+	PowerUpSpawnRate    float64           `json:"powerUpSpawnRate"`              // Chance (0-1) that a destroyed block drops a power-up (random mode)
+	HiddenPowerUps      map[string]int    `json:"hiddenPowerUps,omitempty"`      // Power-ups of each type hidden under blocks (hidden mode)
+	Rules               Rules             `json:"rules"`                         // Starting stats, caps and drop weights
+	Layout              *Layout           `json:"layout,omitempty"`              // Map to play on; nil means ClassicLayout
+	Generator           *GeneratorOptions `json:"generator,omitempty"`           // Generate a new map every round from its seed instead of using Layout
+	MoveInterval        time.Duration     `json:"moveInterval"`                  // Time between steps at speed 1.0; divided by the player's speed
+	Invulnerability     time.Duration     `json:"invulnerability"`               // How long a player cannot be hit again after losing a life
+	Teams               int               `json:"teams,omitempty"`               // Number of teams, 0 for free-for-all; the last team standing wins
+	FriendlyFire        bool              `json:"friendlyFire,omitempty"`        // Teammates' bombs hurt (a player's own always do)
+	RespawnOnHit        bool              `json:"respawnOnHit"`                  // Send a player back to their start slot after losing a life
+	TimeLimit           time.Duration     `json:"timeLimit,omitempty"`           // Match time before sudden death begins; 0 for no limit
+	SuddenDeathInterval time.Duration     `json:"suddenDeathInterval,omitempty"` // Time between two tiles closing in sudden death
+	TickDuration        time.Duration     `json:"tickDuration"`                  // Length of one simulation tick
+	BotDifficulty       string            `json:"botDifficulty,omitempty"`       // Difficulty of bots filling the lobby unless the lobby picks one
+	Seed                int64             `json:"seed,omitempty"`                // Random seed of every round; 0 picks a new one per round
+}
+
+// DefaultSettings returns the classic 4-player rules.
+func DefaultSettings() Settings {
+	return Settings{
+		MaxPlayers:       MAX_PLAYERS,
+		PowerUpMode:      PowerUpModeRandom,
+		PowerUpSpawnRate: 0.3,
+		HiddenPowerUps: map[string]int{
+			PowerUpSpeed:    4,
+			PowerUpBomb:     6,
+			PowerUpFlame:    6,
+			PowerUpKick:     2,
+			PowerUpPunch:    1,
+			PowerUpRemote:   1,
+			PowerUpPierce:   1,
+			PowerUpFullFire: 1,
+			PowerUpSkull:    2,
+		},
+		Rules:               ClassicRules(),
+		MoveInterval:        80 * time.Millisecond,
+		Invulnerability:     2 * time.Second,
+		RespawnOnHit:        false,
+		TimeLimit:           3 * time.Minute,
+		SuddenDeathInterval: 250 * time.Millisecond,
+		TickDuration:        time.Second / 60,
+		BotDifficulty:       BotNormal,
+	}
+}
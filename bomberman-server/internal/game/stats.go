@@ -0,0 +1,128 @@
+package game
+
+import (
+	"sort"
+	"time"
+)
+
+// PlayerStats are the per-player counters of the current match.
+// Kills and deaths count lives, so a player can be killed up to Rules.Lives times.
+type PlayerStats struct {
+	Kills             int   `json:"kills"`             // Lives taken from opponents
+	TeamKills         int   `json:"teamKills"`         // Lives taken from teammates, with friendly fire on
+	Deaths            int   `json:"deaths"`            // Lives lost, including suicides
+	Suicides          int   `json:"suicides"`          // Lives lost to the player's own bombs
+	BlocksDestroyed   int   `json:"blocksDestroyed"`   // Destructible blocks destroyed by the player's bombs
+	PowerUpsCollected int   `json:"powerUpsCollected"` // Power-ups picked up
+	BombsPlaced       int   `json:"bombsPlaced"`       // Bombs placed
+	SurvivalTimeMs    int64 `json:"survivalTimeMs"`    // Time from match start to elimination (or match end)
+}
+
+// Standing is one row of the final match table
+type Standing struct {
+	Place        int         `json:"place"` // 1 is the winner (every player of the winning team); players knocked out together share a place
+	PlayerID     string      `json:"playerId"`
+	PlayerName   string      `json:"playerName"`
+	PlayerNumber int         `json:"playerNumber"`
+	Team         int         `json:"team,omitempty"`
+	Survived     bool        `json:"survived"`
+	Stats        PlayerStats `json:"stats"`
+}
+
+// MatchResult is broadcast as match_result when a match ends
+type MatchResult struct {
+	GameID     string     `json:"gameId"`
+	WinnerID   string     `json:"winnerId,omitempty"`
+	WinnerName string     `json:"winnerName,omitempty"`
+	WinnerTeam int        `json:"winnerTeam,omitempty"` // Set in team mode instead of WinnerID and WinnerName
+	Draw       bool       `json:"draw"`
+	Timeout    bool       `json:"timeout,omitempty"` // Sudden death ran out with more than one player or team standing; always a draw
+	DurationMs int64      `json:"durationMs"`
+	Seed       int64      `json:"seed"` // Replaying the match with this seed gives the same power-ups
+	Standings  []Standing `json:"standings"`
+}
+
+// buildMatchResult ranks players by how long they stayed in the match.
+// A single survivor wins; no survivors (everyone died in the same blast) is a draw.
+// In team mode the team of the survivors wins and all its players share first place,
+// fallen teammates included. A match that times out with several sides standing is a
+// draw, the survivors sharing first place.
+func (g *Game) buildMatchResult(now time.Time) *MatchResult {
+	result := &MatchResult{
+		GameID:     g.ID,
+		DurationMs: now.Sub(g.StartTime).Milliseconds(),
+		Seed:       g.Seed,
+		Standings:  make([]Standing, 0, len(g.Players)),
+	}
+
+	players := g.PlayersInSlotOrder()
+	for _, p := range players {
+		end := now
+		if p.Eliminated && !p.EliminatedAt.IsZero() {
+			end = p.EliminatedAt
+		}
+		p.Stats.SurvivalTimeMs = end.Sub(g.StartTime).Milliseconds()
+	}
+
+	// The winning team, if one is left standing
+	winnerTeam := 0
+	if g.Settings.Teams > 0 && g.sidesAlive() == 1 {
+		for _, p := range players {
+			if p.IsActive() {
+				winnerTeam = p.Team
+			}
+		}
+	}
+	won := func(p *Player) bool { return winnerTeam != 0 && p.Team == winnerTeam }
+
+	// The winning team, then survivors, then the longest surviving; slot order breaks ties
+	sort.SliceStable(players, func(i, j int) bool {
+		if won(players[i]) != won(players[j]) {
+			return won(players[i])
+		}
+		if players[i].IsActive() != players[j].IsActive() {
+			return players[i].IsActive()
+		}
+		return players[i].Stats.SurvivalTimeMs > players[j].Stats.SurvivalTimeMs
+	})
+
+	survivors := 0
+	for i, p := range players {
+		place := i + 1
+		if i > 0 && (sameFinish(players[i-1], p) || won(players[i-1]) && won(p)) {
+			place = result.Standings[i-1].Place
+		}
+		if p.IsActive() {
+			survivors++
+		}
+		result.Standings = append(result.Standings, Standing{
+			Place:        place,
+			PlayerID:     p.ID,
+			PlayerName:   p.Nickname,
+			PlayerNumber: p.Number,
+			Team:         p.Team,
+			Survived:     p.IsActive(),
+			Stats:        p.Stats,
+		})
+	}
+
+	switch {
+	case winnerTeam != 0:
+		result.WinnerTeam = winnerTeam
+	case g.Settings.Teams == 0 && survivors == 1:
+		result.WinnerID = result.Standings[0].PlayerID
+		result.WinnerName = result.Standings[0].PlayerName
+	default:
+		result.Draw = true
+		result.Timeout = g.suddenDeathOver() && g.sidesAlive() > 1
+	}
+	return result
+}
+
+// sameFinish reports whether two players finished the match at the same moment
+func sameFinish(a, b *Player) bool {
+	if a.IsActive() || b.IsActive() {
+		return a.IsActive() && b.IsActive()
+	}
+	return a.EliminatedAt.Equal(b.EliminatedAt)
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"log"
+	"time"
+)
+
+// ClosingTilesShown is how many of the next tiles to close are sent with the state
+const ClosingTilesShown = 8
+
+// suddenDeathAt is when sudden death begins, zero if the match has no time limit.
+// Callers must hold g.Mutex.
+func (g *Game) suddenDeathAt() time.Time {
+	if g.Settings.TimeLimit <= 0 || g.StartTime.IsZero() {
+		return time.Time{}
+	}
+	return g.StartTime.Add(g.Settings.TimeLimit)
+}
+
+// spiralOrder lists the tiles sudden death closes, from the border inwards, clockwise
+// from the top-left corner of each ring. Indestructible tiles are skipped and the 3x3
+// square in the centre of the map stays open.
+func spiralOrder(gm *GameMap) []Position {
+	centre := Position{X: gm.Width / 2, Y: gm.Height / 2}
+	order := make([]Position, 0, gm.Width*gm.Height)
+	add := func(x, y int) {
+		dx, dy := x-centre.X, y-centre.Y
+		if dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1 {
+			return
+		}
+		if gm.Blocks[y][x] != Indestructible {
+			order = append(order, Position{X: x, Y: y})
+		}
+	}
+
+	top, left, bottom, right := 0, 0, gm.Height-1, gm.Width-1
+	for top <= bottom && left <= right {
+		for x := left; x <= right; x++ {
+			add(x, top)
+		}
+		for y := top + 1; y <= bottom; y++ {
+			add(right, y)
+		}
+		if top < bottom {
+			for x := right - 1; x >= left; x-- {
+				add(x, bottom)
+			}
+		}
+		if left < right {
+			for y := bottom - 1; y > top; y-- {
+				add(left, y)
+			}
+		}
+		top, left, bottom, right = top+1, left+1, bottom-1, right-1
+	}
+	return order
+}
+
+// processSuddenDeath starts sudden death once the time limit is up and then closes one
+// tile of the spiral every Settings.SuddenDeathInterval. Callers must hold g.Mutex.
+func (g *Game) processSuddenDeath(now time.Time) {
+	start := g.suddenDeathAt()
+	if start.IsZero() || now.Before(start) {
+		return
+	}
+	if !g.SuddenDeath {
+		g.SuddenDeath = true
+		g.spiral = spiralOrder(g.Map)
+		g.closed = 0
+		log.Printf("Game %s: time is up, sudden death closes %d tiles", g.ID, len(g.spiral))
+	}
+	for g.closed < len(g.spiral) && !now.Before(g.closeAt(g.closed)) {
+		g.closeTile(g.spiral[g.closed], now)
+		g.closed++
+	}
+}
+
+// closeAt is when the i-th tile of the spiral closes
+func (g *Game) closeAt(i int) time.Time {
+	return g.suddenDeathAt().Add(time.Duration(i) * g.Settings.SuddenDeathInterval)
+}
+
+// closeTile fills a tile with an indestructible block. Whatever was there is lost:
+// bombs are refunded to their owners without going off, and players are eliminated.
+func (g *Game) closeTile(pos Position, now time.Time) {
+	g.Map.Blocks[pos.Y][pos.X] = Indestructible
+	g.Map.revealPowerUp(pos)
+
+	for id, powerUp := range g.PowerUps {
+		if powerUp.Position == pos {
+			delete(g.PowerUps, id)
+		}
+	}
+	for _, bomb := range g.bombsAt(pos) {
+		if owner, ok := g.Players[bomb.PlayerID]; ok {
+			owner.BombExploded()
+		}
+		delete(g.Bombs, bomb.ID)
+	}
+	for _, p := range g.PlayersInSlotOrder() {
+		if p.Position == pos && p.IsActive() {
+			p.Stats.Deaths += p.Lives
+			p.Lives = 0
+			g.eliminatePlayer(p, "", CauseSuddenDeath, now)
+		}
+	}
+}
+
+// clearSuddenDeath forgets the sudden death of the previous match
+func (g *Game) clearSuddenDeath() {
+	g.SuddenDeath = false
+	g.spiral = nil
+	g.closed = 0
+}
+
+// suddenDeathOver reports whether sudden death has closed every tile it will close
+func (g *Game) suddenDeathOver() bool {
+	return g.SuddenDeath && g.closed >= len(g.spiral)
+}
+
+// TimeLeft is how long until sudden death begins or, once it has, until the last
+// tile closes and the match times out. It is zero without a time limit. Callers must
+// hold g.Mutex.
+func (g *Game) TimeLeft(now time.Time) time.Duration {
+	start := g.suddenDeathAt()
+	if start.IsZero() {
+		return 0
+	}
+	end := start
+	if g.SuddenDeath {
+		end = g.closeAt(len(g.spiral) - 1)
+	}
+	if end.Before(now) {
+		return 0
+	}
+	return end.Sub(now)
+}
+
+// ClosingTiles lists the next tiles sudden death will close, at most n, in the order
+// they close. Callers must hold g.Mutex.
+func (g *Game) ClosingTiles(n int) []Position {
+	if !g.SuddenDeath || g.closed >= len(g.spiral) {
+		return nil
+	}
+	end := g.closed + n
+	if end > len(g.spiral) {
+		end = len(g.spiral)
+	}
+	return append([]Position(nil), g.spiral[g.closed:end]...)
+}
@@ -0,0 +1,130 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// MaxTeams is the most teams a game can be split into
+const MaxTeams = MAX_PLAYERS / 2
+
+// ValidateTeams checks a team count: 0 for free-for-all, or 2 to MaxTeams
+func ValidateTeams(teams int) error {
+	if teams != 0 && (teams < 2 || teams > MaxTeams) {
+		return fmt.Errorf("teams must be 0 (free-for-all) or between 2 and %d, got %d", MaxTeams, teams)
+	}
+	return nil
+}
+
+// teamSize is the most players one team can hold, so no team can take more than its
+// share of the lobby. Callers must hold g.Mutex.
+func (g *Game) teamSize() int {
+	return (g.Capacity() + g.Settings.Teams - 1) / g.Settings.Teams
+}
+
+// teamCounts returns how many players each team has, indexed by team number
+func (g *Game) teamCounts() []int {
+	counts := make([]int, g.Settings.Teams+1)
+	for _, p := range g.Players {
+		if p.Team >= 1 && p.Team <= g.Settings.Teams {
+			counts[p.Team]++
+		}
+	}
+	return counts
+}
+
+// assignTeam puts a new player on the team with the fewest players, the lowest
+// number first. It does nothing in free-for-all. Callers must hold g.Mutex.
+func (g *Game) assignTeam(player *Player) {
+	if g.Settings.Teams == 0 {
+		return
+	}
+	counts := g.teamCounts()
+	player.Team = 1
+	for team := 2; team <= g.Settings.Teams; team++ {
+		if counts[team] < counts[player.Team] {
+			player.Team = team
+		}
+	}
+}
+
+// SetTeam moves a player to another team. Teams can only be picked in the lobby and
+// a full team takes nobody else.
+func (g *Game) SetTeam(playerID string, team int) error {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	player, ok := g.Players[playerID]
+	if !ok {
+		return errors.New("player not found")
+	}
+	if g.Settings.Teams == 0 {
+		return errors.New("this game has no teams")
+	}
+	if g.State != GameWaiting {
+		return errors.New("teams can only be changed in the lobby")
+	}
+	if team < 1 || team > g.Settings.Teams {
+		return fmt.Errorf("team must be between 1 and %d", g.Settings.Teams)
+	}
+	if player.Team == team {
+		return nil
+	}
+	if g.teamCounts()[team] >= g.teamSize() {
+		return fmt.Errorf("team %d is full", team)
+	}
+	player.Team = team
+	log.Printf("Player %s (%s) joined team %d", player.Nickname, player.ID, team)
+	return nil
+}
+
+// balanceTeams deals players out to the teams in slot order if they all picked the
+// same one, so a match never starts already won. Callers must hold g.Mutex.
+func (g *Game) balanceTeams() {
+	if g.Settings.Teams == 0 || len(g.Players) < 2 {
+		return
+	}
+	occupied := 0
+	for _, count := range g.teamCounts() {
+		if count > 0 {
+			occupied++
+		}
+	}
+	if occupied >= 2 {
+		return
+	}
+	log.Printf("Game %s: every player is on one team; balancing teams", g.ID)
+	for i, p := range g.PlayersInSlotOrder() {
+		p.Team = i%g.Settings.Teams + 1
+	}
+}
+
+// sideOf is what a player fights for: their team, or in free-for-all just themselves
+func (g *Game) sideOf(player *Player) string {
+	if g.Settings.Teams > 0 {
+		return fmt.Sprintf("team %d", player.Team)
+	}
+	return player.ID
+}
+
+// sidesAlive counts the teams (or in free-for-all, the players) with lives left
+func (g *Game) sidesAlive() int {
+	sides := make(map[string]bool)
+	for _, p := range g.Players {
+		if p.Lives > 0 {
+			sides[g.sideOf(p)] = true
+		}
+	}
+	return len(sides)
+}
+
+// spares reports whether an explosion set off by owner leaves player unharmed: with
+// friendly fire off, teammates' bombs do not hurt. A player's own bombs always do.
+func (g *Game) spares(ownerID string, player *Player) bool {
+	if g.Settings.Teams == 0 || g.Settings.FriendlyFire || ownerID == player.ID {
+		return false
+	}
+	owner, ok := g.Players[ownerID]
+	return ok && owner.Team == player.Team
+}
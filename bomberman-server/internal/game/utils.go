@@ -2,6 +2,7 @@ package game
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 )
@@ -16,3 +17,13 @@ func GenerateUUID() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x",
 		b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
+
+// GenerateToken generates a random secret used to authenticate a player's session
+func GenerateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		// Never fall back to a guessable value for a secret
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+// Package maps serves the map layouts stored in a directory.
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"bomberman-server/internal/game"
+)
+
+// Map files are named <name>.txt (tile rows only) or <name>.json (a game.Layout)
+const (
+	textSuffix = ".txt"
+	jsonSuffix = ".json"
+)
+
+var (
+	ErrNotFound    = errors.New("map not found")
+	ErrInvalidName = errors.New("invalid map name")
+)
+
+// validName keeps map names to plain file names, so a name can never point outside
+// the maps directory
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Summary describes a map without its tiles
+type Summary struct {
+	Name     string `json:"name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Spawns   int    `json:"spawns"`   // Most players the map can seat
+	PowerUps int    `json:"powerUps"` // Power-ups placed by the map itself
+}
+
+// Summarize describes a layout
+func Summarize(layout *game.Layout) Summary {
+	return Summary{
+		Name:     layout.Name,
+		Width:    layout.Width(),
+		Height:   layout.Height(),
+		Spawns:   len(layout.Spawns()),
+		PowerUps: len(layout.PowerUps),
+	}
+}
+
+// Catalog loads layouts from a directory. The built-in classic layout is always
+// available, and a file of the same name replaces it.
+type Catalog struct {
+	dir string // Empty when only the built-in layout is available
+}
+
+// NewCatalog serves the layouts in dir. An empty dir serves only the built-in layout;
+// a dir that does not exist is an error.
+func NewCatalog(dir string) (*Catalog, error) {
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("opening maps directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("opening maps directory: %s is not a directory", dir)
+		}
+	}
+	return &Catalog{dir: dir}, nil
+}
+
+// Load reads and validates the layout with the given name.
+func (c *Catalog) Load(name string) (*game.Layout, error) {
+	if !validName.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	if c.dir != "" {
+		for _, suffix := range []string{jsonSuffix, textSuffix} {
+			data, err := os.ReadFile(filepath.Join(c.dir, name+suffix))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("reading map %s: %w", name, err)
+			}
+			if suffix == jsonSuffix {
+				return game.ParseLayoutJSON(name, data)
+			}
+			return game.ParseLayoutText(name, data)
+		}
+	}
+
+	if name == game.ClassicLayoutName {
+		return game.ClassicLayout(), nil
+	}
+	return nil, ErrNotFound
+}
+
+// List describes every valid map, sorted by name. Invalid files are skipped.
+func (c *Catalog) List() ([]Summary, error) {
+	names := map[string]bool{game.ClassicLayoutName: true}
+	if c.dir != "" {
+		entries, err := os.ReadDir(c.dir)
+		if err != nil {
+			return nil, fmt.Errorf("listing maps: %w", err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				continue
+			}
+			for _, suffix := range []string{jsonSuffix, textSuffix} {
+				if strings.HasSuffix(name, suffix) {
+					names[strings.TrimSuffix(name, suffix)] = true
+				}
+			}
+		}
+	}
+
+	summaries := make([]Summary, 0, len(names))
+	for name := range names {
+		layout, err := c.Load(name)
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, Summarize(layout))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries, nil
+}
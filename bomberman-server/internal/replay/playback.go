@@ -0,0 +1,195 @@
+package replay
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"bomberman-server/internal/game"
+)
+
+// MaxSpeed is the fastest playback rate a viewer can ask for
+const MaxSpeed = 8
+
+// maxCatchUpTicks bounds how many ticks are simulated back to back after a stall,
+// like game.Loop does for live games
+const maxCatchUpTicks = 10
+
+var ErrInvalidSpeed = fmt.Errorf("speed must be greater than 0 and at most %d", MaxSpeed)
+
+// Status is where a playback currently is
+type Status struct {
+	PositionMs int64   `json:"positionMs"`
+	DurationMs int64   `json:"durationMs"`
+	Speed      float64 `json:"speed"`
+	Paused     bool    `json:"paused"`
+	Finished   bool    `json:"finished"` // Reached the end of the match
+}
+
+// Playback re-simulates a recorded match through a game.Game, in real time scaled by
+// its speed. Seeking backwards restores the snapshot and simulates forward again,
+// which is cheap since ticks need no waiting.
+type Playback struct {
+	recording *game.Recording
+	game      *game.Game
+	clock     game.Clock
+
+	tick   uint64 // Ticks simulated since the start of the match
+	next   int    // Index of the next recorded action to apply
+	speed  float64
+	paused bool
+
+	// Guards everything above, and keeps event draining from interleaving with a seek
+	mutex sync.Mutex
+
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPlayback prepares a recording for playback at normal speed.
+func NewPlayback(rec *game.Recording, clock game.Clock) (*Playback, error) {
+	g, err := game.NewReplayGame(rec)
+	if err != nil {
+		return nil, err
+	}
+	return &Playback{
+		recording: rec,
+		game:      g,
+		clock:     clock,
+		speed:     1,
+		quit:      make(chan struct{}),
+	}, nil
+}
+
+// Game returns the game the recording is played into. The same game is reused
+// when seeking, so it can be handed to a hub once.
+func (p *Playback) Game() *game.Game {
+	return p.game
+}
+
+// Recording returns the recording being played
+func (p *Playback) Recording() *game.Recording {
+	return p.recording
+}
+
+// Run plays the recording until Stop is called. Reaching the end leaves the final
+// state on screen; a seek can then rewind it.
+func (p *Playback) Run() {
+	step := p.recording.Settings.TickDuration
+	last := p.clock.Now()
+	var owed time.Duration // Match time that should have been simulated but was not yet
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-p.clock.After(step):
+		}
+
+		now := p.clock.Now()
+		p.mutex.Lock()
+		if !p.paused {
+			owed += time.Duration(float64(now.Sub(last)) * p.speed)
+		}
+		last = now
+
+		if limit := time.Duration(maxCatchUpTicks*p.speed) * step; owed > limit {
+			owed = limit
+		}
+		for ; owed >= step && p.tick < p.recording.Ticks; owed -= step {
+			p.stepLocked()
+		}
+		if p.tick >= p.recording.Ticks {
+			owed = 0
+		}
+		p.mutex.Unlock()
+	}
+}
+
+// Stop ends Run.
+func (p *Playback) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.quit)
+	})
+}
+
+// stepLocked applies the actions recorded for the next tick and runs it.
+// Callers must hold p.mutex.
+func (p *Playback) stepLocked() {
+	actions := p.recording.Actions
+	for p.next < len(actions) && actions[p.next].Tick <= p.tick+1 {
+		// Actions rejected now were rejected live too; the recording reproduces that
+		p.game.ApplyRecordedAction(actions[p.next])
+		p.next++
+	}
+	p.game.Update()
+	p.tick++
+}
+
+// SetSpeed changes the playback rate, e.g. 1 for real time or 2 for double speed
+func (p *Playback) SetSpeed(speed float64) error {
+	if speed <= 0 || speed > MaxSpeed {
+		return ErrInvalidSpeed
+	}
+	p.mutex.Lock()
+	p.speed = speed
+	p.mutex.Unlock()
+	return nil
+}
+
+// SetPaused pauses or resumes playback
+func (p *Playback) SetPaused(paused bool) {
+	p.mutex.Lock()
+	p.paused = paused
+	p.mutex.Unlock()
+}
+
+// Seek jumps to a position in the match, clamped to its length. Events produced while
+// simulating up to it are discarded: they belong to the skipped part of the match.
+func (p *Playback) Seek(position time.Duration) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	target := uint64(0)
+	if position > 0 {
+		target = uint64(position / p.recording.Settings.TickDuration)
+	}
+	if target > p.recording.Ticks {
+		target = p.recording.Ticks
+	}
+
+	if target < p.tick {
+		if err := p.game.Restore(p.recording); err != nil {
+			return err
+		}
+		p.tick = 0
+		p.next = 0
+	}
+	for p.tick < target {
+		p.stepLocked()
+	}
+	p.game.DrainEvents()
+	return nil
+}
+
+// DrainEvents returns the game events produced since the last call. Use it instead of
+// Game().DrainEvents so events skipped by a seek are never delivered.
+func (p *Playback) DrainEvents() []game.Event {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.game.DrainEvents()
+}
+
+// Status reports the current position and playback settings
+func (p *Playback) Status() Status {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	step := p.recording.Settings.TickDuration
+	return Status{
+		PositionMs: (time.Duration(p.tick) * step).Milliseconds(),
+		DurationMs: p.recording.Duration().Milliseconds(),
+		Speed:      p.speed,
+		Paused:     p.paused,
+		Finished:   p.tick >= p.recording.Ticks,
+	}
+}
@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"bomberman-server/internal/game"
+)
+
+// FormatVersion is the version of the replay file layout. It is bumped whenever
+// game.Recording changes in a way older replays cannot be re-simulated with;
+// files with any other version are refused instead of replaying wrongly.
+const FormatVersion = 4
+
+// formatName identifies replay files
+const formatName = "bomberman-replay"
+
+// fileSuffix is appended to the recording ID to name its file
+const fileSuffix = ".replay.json"
+
+var (
+	ErrNotFound  = errors.New("replay not found")
+	ErrInvalidID = errors.New("invalid replay id")
+)
+
+// validID matches the IDs generated by game.GenerateUUID, so an ID can never
+// point outside the replay directory
+var validID = regexp.MustCompile(`^[0-9a-f-]{1,64}$`)
+
+// file is the on-disk form of a replay
+type file struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	Recording *game.Recording `json:"recording"`
+}
+
+// PlayerInfo identifies a player of a recorded match
+type PlayerInfo struct {
+	ID       string `json:"id"`
+	Nickname string `json:"nickname"`
+	Number   int    `json:"number"`
+}
+
+// Summary describes a replay without its action stream
+type Summary struct {
+	ID         string            `json:"id"`
+	GameID     string            `json:"gameId"`
+	StartedAt  int64             `json:"startedAt"` // Unix timestamp (milliseconds)
+	DurationMs int64             `json:"durationMs"`
+	Seed       int64             `json:"seed"`
+	Players    []PlayerInfo      `json:"players"`
+	Result     *game.MatchResult `json:"result,omitempty"` // Nil if the match was cut short
+}
+
+// Summarize describes a recording
+func Summarize(rec *game.Recording) Summary {
+	summary := Summary{
+		ID:         rec.ID,
+		GameID:     rec.GameID,
+		StartedAt:  rec.StartedAt.UnixMilli(),
+		DurationMs: rec.Duration().Milliseconds(),
+		Seed:       rec.Seed,
+		Players:    make([]PlayerInfo, 0, len(rec.Snapshot.Players)),
+		Result:     rec.Result,
+	}
+	for _, p := range rec.Snapshot.Players {
+		summary.Players = append(summary.Players, PlayerInfo{ID: p.ID, Nickname: p.Nickname, Number: p.Number})
+	}
+	return summary
+}
+
+// Store keeps one file per recorded match in a directory.
+type Store struct {
+	dir string
+}
+
+// NewStore opens the replay directory, creating it if needed.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating replay directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", ErrInvalidID
+	}
+	return filepath.Join(s.dir, id+fileSuffix), nil
+}
+
+// Save writes a recording. The file is written under a temporary name first so a
+// crash never leaves a truncated replay behind.
+func (s *Store) Save(rec *game.Recording) error {
+	path, err := s.path(rec.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(file{Format: formatName, Version: FormatVersion, Recording: rec})
+	if err != nil {
+		return fmt.Errorf("encoding replay %s: %w", rec.ID, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing replay %s: %w", rec.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing replay %s: %w", rec.ID, err)
+	}
+	return nil
+}
+
+// Load reads a recording, refusing files written in another format version.
+func (s *Store) Load(id string) (*game.Recording, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading replay %s: %w", id, err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decoding replay %s: %w", id, err)
+	}
+	if f.Format != formatName || f.Recording == nil {
+		return nil, fmt.Errorf("replay %s: not a replay file", id)
+	}
+	if f.Version != FormatVersion {
+		return nil, fmt.Errorf("replay %s: unsupported format version %d (this server reads version %d)", id, f.Version, FormatVersion)
+	}
+	return f.Recording, nil
+}
+
+// List describes every readable replay, newest first. Unreadable files are skipped.
+func (s *Store) List() ([]Summary, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing replays: %w", err)
+	}
+
+	summaries := make([]Summary, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		rec, err := s.Load(strings.TrimSuffix(name, fileSuffix))
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, Summarize(rec))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt > summaries[j].StartedAt
+	})
+	return summaries, nil
+}
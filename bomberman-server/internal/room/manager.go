@@ -0,0 +1,212 @@
+package room
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"bomberman-server/internal/bot"
+	"bomberman-server/internal/game"
+	"bomberman-server/internal/replay"
+	"bomberman-server/internal/websocket"
+)
+
+// DefaultRoomID is the room used when a client does not ask for a specific one.
+const DefaultRoomID = "default"
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomExists   = errors.New("room already exists")
+	ErrDefaultRoom  = errors.New("the default room cannot be removed")
+)
+
+// Room is a single game instance together with its own broadcast hub.
+type Room struct {
+	ID        string
+	Name      string
+	Game      *game.Game
+	Loop      *game.Loop     // Advances Game at a fixed tick rate
+	Bots      *bot.Driver    // Plays the game's bots, if any
+	Hub       *websocket.Hub // Broadcasts Game to the room's clients
+	CreatedAt time.Time
+}
+
+// Info is the public summary of a room returned by the HTTP API.
+type Info struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	State       int    `json:"state"`
+	PlayerCount int    `json:"playerCount"`
+	MaxPlayers  int    `json:"maxPlayers"`  // Settings.MaxPlayers, or fewer if the map has fewer spawns
+	Seed        int64  `json:"seed"`        // Random seed of the current round
+	PowerUpMode string `json:"powerUpMode"` // How power-ups appear, see game.PowerUpModeRandom
+	Rules       string `json:"rules"`       // Name of the rule set
+	Teams       int    `json:"teams"`       // Number of teams, 0 for free-for-all
+	Map         string `json:"map"`         // Name of the map layout
+	CreatedAt   int64  `json:"createdAt"`   // Unix timestamp (milliseconds)
+}
+
+// Info returns a snapshot of the room's current status.
+func (r *Room) Info() Info {
+	r.Game.Mutex.RLock()
+	defer r.Game.Mutex.RUnlock()
+	return Info{
+		ID:          r.ID,
+		Name:        r.Name,
+		State:       int(r.Game.State),
+		PlayerCount: len(r.Game.Players),
+		MaxPlayers:  r.Game.Capacity(),
+		Seed:        r.Game.Seed,
+		PowerUpMode: r.Game.Settings.PowerUpMode,
+		Rules:       r.Game.Settings.Rules.Name,
+		Teams:       r.Game.Settings.Teams,
+		Map:         r.Game.Map.Name,
+		CreatedAt:   r.CreatedAt.UnixMilli(),
+	}
+}
+
+// Manager holds every running room on the server.
+type Manager struct {
+	rooms        map[string]*Room
+	gameSettings game.Settings
+	hubSettings  websocket.Settings
+	replays      *replay.Store // Nil when recording is disabled
+	mutex        sync.RWMutex
+}
+
+// NewManager creates a room manager with the default room already running.
+// Every room it creates uses the given game rules and connection limits, and saves
+// its matches to replays unless that is nil.
+func NewManager(gameSettings game.Settings, hubSettings websocket.Settings, replays *replay.Store) *Manager {
+	m := &Manager{
+		rooms:        make(map[string]*Room),
+		gameSettings: gameSettings,
+		hubSettings:  hubSettings,
+		replays:      replays,
+	}
+	if _, err := m.createRoom(DefaultRoomID, "Default", gameSettings); err != nil {
+		log.Fatalf("Could not create default room: %v", err)
+	}
+	return m
+}
+
+// DefaultSettings returns the game rules rooms get unless they override them.
+func (m *Manager) DefaultSettings() game.Settings {
+	return m.gameSettings
+}
+
+// CreateRoom creates a new room with a generated ID, playing by the given rules, and
+// starts its hub.
+func (m *Manager) CreateRoom(name string, settings game.Settings) (*Room, error) {
+	return m.createRoom(game.GenerateUUID(), name, settings)
+}
+
+func (m *Manager) createRoom(id, name string, settings game.Settings) (*Room, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.rooms[id]; exists {
+		return nil, ErrRoomExists
+	}
+	if name == "" {
+		name = id
+	}
+
+	gameInstance, err := game.NewGame(settings)
+	if err != nil {
+		return nil, err
+	}
+	if m.replays != nil {
+		gameInstance.OnRecording = func(rec *game.Recording) {
+			go m.saveRecording(id, rec) // Called under the game lock, keep disk I/O out of it
+		}
+	}
+	r := &Room{
+		ID:        id,
+		Name:      name,
+		Game:      gameInstance,
+		Loop:      game.NewLoop(gameInstance, game.RealClock{}),
+		Bots:      bot.NewDriver(gameInstance),
+		Hub:       websocket.NewHub(gameInstance, m.hubSettings),
+		CreatedAt: time.Now(),
+	}
+	m.rooms[id] = r
+	go r.Loop.Run()
+	go r.Bots.Run()
+	go r.Hub.Run()
+
+	log.Printf("Room %s (%s) created", r.Name, r.ID)
+	return r, nil
+}
+
+// saveRecording writes a finished match of a room to the replay store
+func (m *Manager) saveRecording(roomID string, rec *game.Recording) {
+	if err := m.replays.Save(rec); err != nil {
+		log.Printf("Could not save replay %s of room %s: %v", rec.ID, roomID, err)
+		return
+	}
+	log.Printf("Saved replay %s of room %s (%d actions)", rec.ID, roomID, len(rec.Actions))
+}
+
+// GetRoom returns the room with the given ID. An empty ID selects the default room.
+func (m *Manager) GetRoom(id string) (*Room, error) {
+	if id == "" {
+		id = DefaultRoomID
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	r, ok := m.rooms[id]
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return r, nil
+}
+
+// ListRooms returns a summary of all rooms, oldest first.
+func (m *Manager) ListRooms() []Info {
+	m.mutex.RLock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, r := range m.rooms {
+		rooms = append(rooms, r)
+	}
+	m.mutex.RUnlock()
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreatedAt.Before(rooms[j].CreatedAt)
+	})
+
+	infos := make([]Info, 0, len(rooms))
+	for _, r := range rooms {
+		infos = append(infos, r.Info())
+	}
+	return infos
+}
+
+// RemoveRoom stops the room's hub, disconnects its clients and forgets the room.
+func (m *Manager) RemoveRoom(id string) error {
+	if id == DefaultRoomID {
+		return ErrDefaultRoom
+	}
+
+	m.mutex.Lock()
+	r, ok := m.rooms[id]
+	if ok {
+		delete(m.rooms, id)
+	}
+	m.mutex.Unlock()
+
+	if !ok {
+		return ErrRoomNotFound
+	}
+
+	r.Game.StopRecording() // A match cut short is still saved, as on a reset
+	r.Loop.Stop()
+	r.Bots.Stop()
+	r.Hub.Stop()
+	log.Printf("Room %s (%s) torn down", r.Name, r.ID)
+	return nil
+}
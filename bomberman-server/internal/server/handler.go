@@ -1,12 +1,20 @@
 package server
 
 import (
-	"bomberman-server/internal/websocket"
 	"bomberman-server/internal/game"
+	"bomberman-server/internal/maps"
+	"bomberman-server/internal/replay"
+	"bomberman-server/internal/room"
+	"bomberman-server/internal/websocket"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/mux"
 	gorillaws "github.com/gorilla/websocket"
 )
 
@@ -16,10 +24,64 @@ var upgrader = gorillaws.Upgrader{
 	},
 }
 
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an {"error": ...} JSON response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"error": message,
+	})
+}
+
+// roomStatus maps room manager errors to HTTP status codes
+func roomStatus(err error) int {
+	switch {
+	case errors.Is(err, room.ErrRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, room.ErrRoomExists), errors.Is(err, room.ErrDefaultRoom):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// replayStatus maps replay store errors to HTTP status codes
+func replayStatus(err error) int {
+	switch {
+	case errors.Is(err, replay.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, replay.ErrInvalidID):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// mapStatus maps map catalog errors to HTTP status codes
+func mapStatus(err error) int {
+	switch {
+	case errors.Is(err, maps.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest // Invalid names and layouts that fail validation
+	}
+}
+
 // handleWebSocket handles WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	log.Println("WebSocket connection request received")
 
+	gameRoom, err := s.Rooms.GetRoom(r.URL.Query().Get("room"))
+	if err != nil {
+		writeError(w, roomStatus(err), err.Error())
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Error upgrading connection:", err)
@@ -29,61 +91,337 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	log.Println("WebSocket connection established")
 
 	client := &websocket.Client{
-		Hub:  s.Hub,
+		Hub:  gameRoom.Hub,
 		Conn: conn,
 		Send: make(chan []byte, 256),
 	}
 
-	s.Hub.Register <- client
-	log.Println("Client registered with hub")
+	if !gameRoom.Hub.RegisterClient(client) {
+		log.Printf("Room %s was torn down before client could register", gameRoom.ID)
+		conn.Close()
+		return
+	}
+	log.Printf("Client registered with hub of room %s", gameRoom.ID)
 
 	// Start goroutines for reading and writing messages
 	go client.ReadMessages()
 	go client.WriteMessages()
 }
 
-// handleJoinGame handles a request to join the game
+// handleJoinGame handles a request to join the game in a room
 func (s *Server) handleJoinGame(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		Nickname string `json:"nickname"`
+		RoomID   string `json:"roomId"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Invalid JSON payload",
-		})
+		writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	// The room can come from the path, the query string or the body
+	roomID := mux.Vars(r)["id"]
+	if roomID == "" {
+		roomID = r.URL.Query().Get("room")
+	}
+	if roomID == "" {
+		roomID = request.RoomID
+	}
+
+	gameRoom, err := s.Rooms.GetRoom(roomID)
+	if err != nil {
+		writeError(w, roomStatus(err), err.Error())
 		return
 	}
 
 	playerID := game.GenerateUUID()
-	player, err := s.Game.AddPlayer(playerID, request.Nickname)
+	player, err := gameRoom.Game.AddPlayer(playerID, request.Nickname)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),  // returns "game is full" as JSON
-		})
+		writeError(w, http.StatusBadRequest, err.Error()) // returns "lobby is full" as JSON
 		return
 	}
 
 	response := map[string]interface{}{
-		"playerID": player.ID,
-		"status":   "joined",
+		"playerID":     player.ID,
+		"sessionToken": player.SessionToken, // Must be sent with the websocket join
+		"roomId":       gameRoom.ID,
+		"status":       "joined",
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 
-// handleGameStatus returns the current game status
+// handleGameStatus returns the current game status of a room
 func (s *Server) handleGameStatus(w http.ResponseWriter, r *http.Request) {
+	gameRoom, err := s.Rooms.GetRoom(r.URL.Query().Get("room"))
+	if err != nil {
+		writeError(w, roomStatus(err), err.Error())
+		return
+	}
+
+	info := gameRoom.Info()
 	response := map[string]interface{}{
-		"state":       s.Game.State,
-		"playerCount": len(s.Game.Players),
+		"roomId":      info.ID,
+		"state":       info.State,
+		"playerCount": info.PlayerCount,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleListRooms returns every room on the server
+func (s *Server) handleListRooms(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rooms": s.Rooms.ListRooms(),
+	})
+}
+
+// handleCreateRoom creates a new room
+func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name           string                 `json:"name"`
+		Seed           *int64                 `json:"seed"`           // Fixes the random seed of every round in the room
+		MaxPlayers     int                    `json:"maxPlayers"`     // Lobby size; the server's if zero, and never more than the map's spawns
+		Teams          *int                   `json:"teams"`          // Number of teams, 0 for free-for-all; the server's if nil
+		FriendlyFire   *bool                  `json:"friendlyFire"`   // Whether teammates' bombs hurt; the server's if nil
+		TimeLimit      string                 `json:"timeLimit"`      // Match time before sudden death, e.g. "90s"; "0" for none, the server's if empty
+		BotDifficulty  string                 `json:"botDifficulty"`  // Difficulty of bots filling the lobby; the server's if empty
+		PowerUpMode    string                 `json:"powerUpMode"`    // "random" or "hidden"; the server's mode if empty
+		HiddenPowerUps map[string]int         `json:"hiddenPowerUps"` // Replaces the server's counts in hidden mode
+		Rules          string                 `json:"rules"`          // Built-in rule set; the server's rules if empty
+		CustomRules    *game.Rules            `json:"customRules"`    // Full rule set, takes precedence over rules
+		Map            string                 `json:"map"`            // Map layout by name; the server's map if empty
+		Generator      *game.GeneratorOptions `json:"generator"`      // Options when map is "generated"; the server's if nil
+	}
+
+	// An empty body is allowed; the room then gets its ID as name and the server's rules
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
+
+	settings := s.Rooms.DefaultSettings()
+	if request.Seed != nil {
+		settings.Seed = *request.Seed
+	}
+	if request.MaxPlayers != 0 {
+		if request.MaxPlayers < 2 || request.MaxPlayers > game.MAX_PLAYERS {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("maxPlayers must be between 2 and %d", game.MAX_PLAYERS))
+			return
+		}
+		settings.MaxPlayers = request.MaxPlayers
+	}
+	if request.Teams != nil {
+		settings.Teams = *request.Teams
+	}
+	if request.FriendlyFire != nil {
+		settings.FriendlyFire = *request.FriendlyFire
+	}
+	if err := game.ValidateTeams(settings.Teams); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if settings.Teams > settings.MaxPlayers {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("teams must not exceed maxPlayers (%d)", settings.MaxPlayers))
+		return
+	}
+	if request.TimeLimit != "" {
+		timeLimit, err := time.ParseDuration(request.TimeLimit)
+		if err != nil || timeLimit < 0 {
+			writeError(w, http.StatusBadRequest, "timeLimit must be a duration such as \"90s\", or \"0\" for no limit")
+			return
+		}
+		settings.TimeLimit = timeLimit
+	}
+	if request.BotDifficulty != "" {
+		if err := game.ValidateBotDifficulty(request.BotDifficulty); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		settings.BotDifficulty = request.BotDifficulty
+	}
+	if request.PowerUpMode != "" {
+		settings.PowerUpMode = request.PowerUpMode
+	}
+	if request.HiddenPowerUps != nil {
+		settings.HiddenPowerUps = request.HiddenPowerUps
+	}
+	if err := game.ValidatePowerUpMode(settings.PowerUpMode, settings.HiddenPowerUps); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch {
+	case request.CustomRules != nil:
+		if err := request.CustomRules.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "customRules: "+err.Error())
+			return
+		}
+		settings.Rules = *request.CustomRules
+		if settings.Rules.Name == "" {
+			settings.Rules.Name = game.RulesCustom
+		}
+	case request.Rules != "":
+		rules, err := game.RulesByName(request.Rules)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		settings.Rules = rules
+	}
+
+	switch {
+	case request.Map == game.GeneratedLayoutName:
+		generator := s.generator
+		if request.Generator != nil {
+			generator = *request.Generator
+		}
+		if err := generator.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "generator: "+err.Error())
+			return
+		}
+		settings.Layout = nil
+		settings.Generator = &generator
+	case request.Map != "":
+		layout, err := s.Maps.Load(request.Map)
+		if err != nil {
+			writeError(w, mapStatus(err), err.Error())
+			return
+		}
+		settings.Layout = layout
+		settings.Generator = nil
+	}
+
+	gameRoom, err := s.Rooms.CreateRoom(request.Name, settings)
+	if err != nil {
+		writeError(w, roomStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, gameRoom.Info())
+}
+
+// handleGetRoom returns a single room
+func (s *Server) handleGetRoom(w http.ResponseWriter, r *http.Request) {
+	gameRoom, err := s.Rooms.GetRoom(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, roomStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, gameRoom.Info())
+}
+
+// handleDeleteRoom tears a room down and disconnects its clients
+func (s *Server) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	if err := s.Rooms.RemoveRoom(mux.Vars(r)["id"]); err != nil {
+		writeError(w, roomStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListMaps returns every map layout rooms can be created with
+func (s *Server) handleListMaps(w http.ResponseWriter, r *http.Request) {
+	summaries, err := s.Maps.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"maps": summaries,
+	})
+}
+
+// handleListReplays returns every recorded match, newest first
+func (s *Server) handleListReplays(w http.ResponseWriter, r *http.Request) {
+	if s.Replays == nil {
+		writeError(w, http.StatusNotFound, "match recording is disabled")
+		return
+	}
+
+	summaries, err := s.Replays.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"replays": summaries,
+	})
+}
+
+// handleGetReplay returns the summary of a single recorded match
+func (s *Server) handleGetReplay(w http.ResponseWriter, r *http.Request) {
+	if s.Replays == nil {
+		writeError(w, http.StatusNotFound, "match recording is disabled")
+		return
+	}
+
+	rec, err := s.Replays.Load(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, replayStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, replay.Summarize(rec))
+}
+
+// handleReplayWebSocket streams a recorded match to a spectator. Every connection gets
+// its own playback, so viewers can pause and seek independently. ?speed= sets the
+// initial playback rate.
+func (s *Server) handleReplayWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.Replays == nil {
+		writeError(w, http.StatusNotFound, "match recording is disabled")
+		return
+	}
+
+	rec, err := s.Replays.Load(r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, replayStatus(err), err.Error())
+		return
+	}
+
+	playback, err := replay.NewPlayback(rec, game.RealClock{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if speed := r.URL.Query().Get("speed"); speed != "" {
+		value, err := strconv.ParseFloat(speed, 64)
+		if err == nil {
+			err = playback.SetSpeed(value)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, replay.ErrInvalidSpeed.Error())
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("Error upgrading connection:", err)
+		return
+	}
+
+	hub := websocket.NewReplayHub(playback, s.hubSettings)
+	go playback.Run()
+	go hub.Run()
+
+	client := &websocket.Client{
+		Hub:  hub,
+		Conn: conn,
+		Send: make(chan []byte, 256),
+	}
+	if !hub.RegisterClient(client) {
+		conn.Close()
+		return
+	}
+	log.Printf("Streaming replay %s", rec.ID)
+
+	go client.ReadMessages()
+	go client.WriteMessages()
 }
@@ -1,11 +1,15 @@
 package server
 
 import (
-    // "log"
+    "fmt"
+    "log"
     "net/http"
-    "sync"
 
+    "bomberman-server/internal/config"
     "bomberman-server/internal/game"
+    "bomberman-server/internal/maps"
+    "bomberman-server/internal/replay"
+    "bomberman-server/internal/room"
     "bomberman-server/internal/websocket"
 
     "github.com/gorilla/mux"
@@ -13,23 +17,52 @@ import (
 
 // Server represents the game server
 type Server struct {
-    Router    *mux.Router
-    Game      *game.Game
-    Hub       *websocket.Hub
-    mutex     sync.Mutex
+    Router  *mux.Router
+    Rooms   *room.Manager
+    Replays *replay.Store // Nil when match recording is disabled
+    Maps    *maps.Catalog // Layouts rooms can be created with
+
+    hubSettings websocket.Settings    // Limits for replay connections, same as rooms
+    generator   game.GeneratorOptions // Used by rooms asking for a generated map without options
 }
 
-// NewServer creates a new server instance
-func NewServer() *Server {
-    gameInstance := game.NewGame()
-    
+// NewServer creates a new server instance driven by cfg. It fails if the configured
+// map cannot be loaded.
+func NewServer(cfg *config.Config) (*Server, error) {
+    catalog, err := maps.NewCatalog(cfg.Maps.Dir)
+    if err != nil {
+        log.Printf("Only the built-in map is available: %v", err)
+        catalog, _ = maps.NewCatalog("")
+    }
+    gameSettings := cfg.GameSettings()
+    if gameSettings.Generator == nil {
+        layout, err := catalog.Load(cfg.Game.Map)
+        if err != nil {
+            return nil, fmt.Errorf("loading map %q: %w", cfg.Game.Map, err)
+        }
+        gameSettings.Layout = layout
+    }
+
+    var replays *replay.Store
+    if cfg.Replay.Dir != "" {
+        store, err := replay.NewStore(cfg.Replay.Dir)
+        if err != nil {
+            log.Printf("Match recording disabled: %v", err)
+        } else {
+            replays = store
+        }
+    }
+
     server := &Server{
-        Router:    mux.NewRouter(),
-        Game:      gameInstance,
-        Hub:       websocket.NewHub(gameInstance),
+        Router:      mux.NewRouter(),
+        Rooms:       room.NewManager(gameSettings, cfg.WebSocketSettings(), replays), // Starts the default room and its hub
+        Replays:     replays,
+        Maps:        catalog,
+        generator:   cfg.GeneratorOptions(),
+        hubSettings: cfg.WebSocketSettings(),
     }
 
-    return server
+    return server, nil
 }
 
 // SetupRoutes configures the server routes
@@ -50,17 +83,28 @@ func (s *Server) SetupRoutes() {
         })
     })
     
-    // Existing routes
+    // Existing routes. The room is picked with ?room=<id> (or "roomId" in the join body)
+    // and falls back to the default room.
     s.Router.HandleFunc("/ws", s.handleWebSocket)
     s.Router.HandleFunc("/api/game/join", s.handleJoinGame).Methods("POST")
     s.Router.HandleFunc("/api/game/status", s.handleGameStatus).Methods("GET")
 
+    // Room management
+    s.Router.HandleFunc("/api/rooms", s.handleListRooms).Methods("GET")
+    s.Router.HandleFunc("/api/rooms", s.handleCreateRoom).Methods("POST")
+    s.Router.HandleFunc("/api/rooms/{id}", s.handleGetRoom).Methods("GET")
+    s.Router.HandleFunc("/api/rooms/{id}", s.handleDeleteRoom).Methods("DELETE")
+    s.Router.HandleFunc("/api/rooms/{id}/join", s.handleJoinGame).Methods("POST")
+
+    // Map layouts rooms can be created with
+    s.Router.HandleFunc("/api/maps", s.handleListMaps).Methods("GET")
+
+    // Recorded matches, played back with /ws/replay?id=<id>
+    s.Router.HandleFunc("/api/replays", s.handleListReplays).Methods("GET")
+    s.Router.HandleFunc("/api/replays/{id}", s.handleGetReplay).Methods("GET")
+    s.Router.HandleFunc("/ws/replay", s.handleReplayWebSocket)
+
     
     // Serve static files
     s.Router.PathPrefix("/").Handler(http.FileServer(http.Dir("../../../bomberman-web"))) // Adjusted path if running from cmd/server
 }
-
-// StartWebSocketHub starts the WebSocket hub
-func (s *Server) StartWebSocketHub() {
-    s.Hub.Run()
-}
\ No newline at end of file
@@ -6,15 +6,13 @@ import (
 	"sync"
 	"time"
 
-	// "bomberman-server/internal/game"
+	"bomberman-server/internal/game"
+
 	"github.com/gorilla/websocket"
 )
 
 const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	writeWait = 10 * time.Second
 )
 
 type Client struct {
@@ -24,15 +22,115 @@ type Client struct {
 	Conn     *websocket.Conn
 	Send     chan []byte
 	mu       sync.RWMutex // Changed from sync.Mutex to sync.RWMutex
+
+	// spectator is set once the bound player is eliminated; the connection then only
+	// receives the game feed and its actions are rejected
+	spectator bool
+
+	// needsSnapshot makes the next state broadcast send this client a full snapshot
+	needsSnapshot bool
+
+	// closed is set once the hub has closed Send; nothing may be sent on it after that
+	closed bool
+}
+
+// closeSend closes the Send channel, once. Only the hub calls it.
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.Send)
+	}
+}
+
+// requestSnapshot asks for a full game state on the next broadcast
+func (c *Client) requestSnapshot() {
+	c.mu.Lock()
+	c.needsSnapshot = true
+	c.mu.Unlock()
+}
+
+// takeSnapshotRequest reports and clears a pending full snapshot request
+func (c *Client) takeSnapshotRequest() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	needed := c.needsSnapshot
+	c.needsSnapshot = false
+	return needed
+}
+
+// becomeSpectator switches the connection to a read-only game feed and tells the client
+func (c *Client) becomeSpectator() {
+	c.mu.Lock()
+	already := c.spectator
+	c.spectator = true
+	playerID := c.ID
+	c.mu.Unlock()
+
+	if already {
+		return
+	}
+	msg := Message{
+		Type:     "spectator_mode",
+		PlayerID: playerID,
+		Payload:  mustMarshal(map[string]string{"reason": "eliminated"}),
+	}
+	if data, err := json.Marshal(msg); err == nil {
+		c.trySend(data)
+	}
+}
+
+// isSpectator reports whether the connection is a read-only spectator feed
+func (c *Client) isSpectator() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.spectator
+}
+
+// playerID returns the player this connection is bound to, or "" before a successful join
+func (c *Client) playerID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ID
+}
+
+// authorize returns the player bound to this connection if claimedID is empty or matches it.
+// The bound player must still be part of the game (it may have been reset since joining).
+func (c *Client) authorize(claimedID string) (string, bool) {
+	bound := c.playerID()
+	if bound == "" || (claimedID != "" && claimedID != bound) {
+		log.Printf("Rejected message from connection bound to %q claiming player %q", bound, claimedID)
+		return "", false
+	}
+	if !c.Hub.game.HasPlayer(bound) {
+		return "", false
+	}
+	return bound, true
+}
+
+// sendError tells the client why one of its messages was rejected
+func (c *Client) sendError(msgType, reason string) {
+	msg := Message{
+		Type:    msgType,
+		Payload: mustMarshal(map[string]string{"error": reason}),
+	}
+	if data, err := json.Marshal(msg); err == nil {
+		c.trySend(data)
+	}
 }
 
 func (c *Client) ReadMessages() {
 	defer func() {
-		c.Hub.Unregister <- c
+		select {
+		case c.Hub.Unregister <- c:
+		case <-c.Hub.quit: // Hub already stopped and closed our Send channel
+		}
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadLimit(maxMessageSize)
+	pongWait := c.Hub.settings.pongWait()
+	c.Conn.SetReadLimit(c.Hub.settings.MaxMessageSize)
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -60,7 +158,7 @@ func (c *Client) ReadMessages() {
 
 func (c *Client) WriteMessages() {
 	log.Printf("Started WriteMessages for client")
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.Hub.settings.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -100,27 +198,69 @@ func (c *Client) WriteMessages() {
 	}
 }
 
+// trySend queues a message for this client without blocking the caller if its buffer
+// is full. Messages for a client the hub has already dropped are discarded.
+func (c *Client) trySend(data []byte) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.Send <- data:
+	default:
+		log.Printf("Dropping message for client %s: send buffer full", c.ID)
+	}
+}
+
 func (c *Client) handleMessage(message Message) {
-	log.Printf("handleMessage: type=%s, playerId=%s, payload=%s", message.Type, message.PlayerID, string(message.Payload))
+	if message.Type == "join" {
+		// Join payloads carry the session token, keep it out of the logs
+		log.Printf("handleMessage: type=%s, playerId=%s", message.Type, message.PlayerID)
+	} else {
+		log.Printf("handleMessage: type=%s, playerId=%s, payload=%s", message.Type, message.PlayerID, string(message.Payload))
+	}
+	if c.Hub.replay != nil {
+		c.handleReplayMessage(message)
+		return
+	}
 	switch message.Type {
 	case "join":
 		var payload struct {
 			Nickname string `json:"nickname"`
+			Token    string `json:"token"`
 		}
 		if err := json.Unmarshal(message.Payload, &payload); err != nil {
 			log.Printf("Failed to unmarshal join payload: %v", err)
 			// Send error back to client
-			errorMsg := Message{Type: "join_error", Payload: mustMarshal(map[string]string{"error": "Invalid join payload"})}
-			if data, marshalErr := json.Marshal(errorMsg); marshalErr == nil {
-				c.Send <- data
-			}
+			c.sendError("join_error", "Invalid join payload")
 			return
 		}
 
+		// A connection acts for exactly one player
+		if bound := c.playerID(); bound != "" && bound != message.PlayerID {
+			log.Printf("Rejected join as %s on connection bound to %s", message.PlayerID, bound)
+			c.sendError("join_error", "connection is already bound to another player")
+			return
+		}
+
+		// Rejoining an existing player requires the session token issued when they joined.
+		// Unknown or missing IDs get a fresh server-generated ID rather than the one asked for.
+		playerID := message.PlayerID
+		if playerID != "" && c.Hub.game.HasPlayer(playerID) {
+			if err := c.Hub.game.Authenticate(playerID, payload.Token); err != nil {
+				log.Printf("Rejected join as %s: %v", playerID, err)
+				c.sendError("join_error", err.Error())
+				return
+			}
+		} else {
+			playerID = game.GenerateUUID()
+		}
+
 		// Attempt to add or rejoin the player in the game logic.
-		player, err := c.Hub.game.AddPlayer(message.PlayerID, payload.Nickname)
+		player, err := c.Hub.game.AddPlayer(playerID, payload.Nickname)
 		if err != nil {
-			log.Printf("Error adding/rejoining player %s (%s) to game: %v", payload.Nickname, message.PlayerID, err.Error())
+			log.Printf("Error adding/rejoining player %s (%s) to game: %v", payload.Nickname, playerID, err.Error())
 			// Send join_error message to client
 			errorDetails := map[string]string{
 				"error":    err.Error(),
@@ -132,7 +272,7 @@ func (c *Client) handleMessage(message Message) {
 				Payload: mustMarshal(errorDetails),
 			}
 			if data, marshalErr := json.Marshal(errorMsg); marshalErr == nil {
-				c.Send <- data
+				c.trySend(data)
 			}
 			// Do not proceed to set client ID/Nickname or send join_ack if AddPlayer failed
 			return
@@ -143,23 +283,31 @@ func (c *Client) handleMessage(message Message) {
 		c.mu.Lock()
 		c.ID = player.ID
 		c.Nickname = player.Nickname
+		c.spectator = false // Rejoining the lobby of a new round makes them a player again
 		c.mu.Unlock()
 		log.Printf("Player %s (%s) successfully processed by Hub for join/rejoin. Client ID/Nickname updated.", c.Nickname, c.ID)
 
-		// Send join_ack to the joining client
+		// Send join_ack to the joining client, with the token needed to rejoin later
 		ackPayload := map[string]string{
-			"nickname": c.Nickname,
-			"playerId": c.ID,
+			"nickname":     player.Nickname,
+			"playerId":     player.ID,
+			"sessionToken": player.SessionToken,
 		}
 		ack := Message{Type: "join_ack", Payload: mustMarshal(ackPayload)}
 		if data, marshalErr := json.Marshal(ack); marshalErr == nil {
 			log.Printf("✅ Sending join_ack to %s with nickname %s", c.ID, c.Nickname)
-			c.Send <- data
+			c.trySend(data)
 		} else {
 			log.Printf("Failed to marshal join_ack: %v", marshalErr)
 			// Potentially return or handle error more gracefully
 		}
 
+		// Eliminated players rejoining a running game only get to watch
+		if c.Hub.game.IsEliminated(player.ID) {
+			c.becomeSpectator()
+			return
+		}
+
 		// Announce player join to all clients via the Hub
 		// player.Number should be correctly assigned by AddPlayer
 		c.Hub.BroadcastPlayerJoined(player.ID, player.Nickname, player.Number)
@@ -184,14 +332,71 @@ func (c *Client) handleMessage(message Message) {
 		if err := json.Unmarshal(message.Payload, &payload); err != nil {
 			return
 		}
-		c.Hub.HandlePlayerAction(payload)
+
+		// Actions always apply to the player bound by join, never to a client-supplied ID
+		playerID, ok := c.authorize(payload.PlayerID)
+		if !ok {
+			c.sendError("action_error", "action rejected: not joined as this player")
+			return
+		}
+		if c.isSpectator() {
+			c.sendError("action_error", game.ErrPlayerEliminated.Error())
+			return
+		}
+		payload.PlayerID = playerID
+		c.Hub.HandlePlayerAction(c, payload)
+
+	case "resync":
+		// The client missed a delta (sequence gap) and needs a full snapshot
+		c.requestSnapshot()
+
+	case "team":
+		var payload struct {
+			Team int `json:"team"`
+		}
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			c.sendError("team_error", "Invalid team payload")
+			return
+		}
+		playerID, ok := c.authorize(message.PlayerID)
+		if !ok {
+			c.sendError("team_error", "team change rejected: not joined as this player")
+			return
+		}
+		if err := c.Hub.game.SetTeam(playerID, payload.Team); err != nil {
+			c.sendError("team_error", err.Error())
+		}
+
+	case "fill_bots":
+		// Fills the free slots of the lobby with bots; an empty payload uses the room's difficulty
+		var payload struct {
+			Difficulty string `json:"difficulty"`
+		}
+		if len(message.Payload) > 0 {
+			if err := json.Unmarshal(message.Payload, &payload); err != nil {
+				c.sendError("bots_error", "Invalid bots payload")
+				return
+			}
+		}
+		if _, ok := c.authorize(message.PlayerID); !ok {
+			c.sendError("bots_error", "adding bots rejected: not joined as this player")
+			return
+		}
+		if _, err := c.Hub.game.FillWithBots(payload.Difficulty); err != nil {
+			c.sendError("bots_error", err.Error())
+		}
 
 	case "restart_game":
-		log.Printf("Received restart_game request from player %s", message.PlayerID)
+		playerID, ok := c.authorize(message.PlayerID)
+		if !ok {
+			c.sendError("action_error", "restart rejected: not joined as this player")
+			return
+		}
+		log.Printf("Received restart_game request from player %s", playerID)
 		// Call the ResetGame method on the game instance via the hub.
 		// This will initiate the 5-second countdown and proper reset sequence.
 		c.Hub.game.ResetGame()
 		// The game state will be broadcast by the hub's regular update loop once reset.
-		log.Printf("Game reset sequence initiated by player %s", message.PlayerID)
+		log.Printf("Game reset sequence initiated by player %s", playerID)
 	}
 }
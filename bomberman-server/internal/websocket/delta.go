@@ -0,0 +1,185 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+
+	"bomberman-server/internal/game"
+)
+
+// stateSnapshot is one broadcast game state plus the pre-marshaled pieces the
+// next tick is diffed against
+type stateSnapshot struct {
+	update      GameStateUpdate
+	meta        []byte
+	players     map[string][]byte
+	playerOrder []string
+	bombs       map[string][]byte
+	powerUps    map[string]game.PowerUp
+	explosions  []byte
+}
+
+// captureState copies the game state under the game lock so it can be marshaled and
+// diffed without racing the game loop or player actions
+func (h *Hub) captureState() *stateSnapshot {
+	g := h.game
+	now := g.Now() // Simulated time, so countdowns match the game loop
+
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+
+	players := g.PlayersInSlotOrder() // Use the ordered list of players
+
+	blocks := make([][]game.BlockType, len(g.Map.Blocks))
+	for y, row := range g.Map.Blocks {
+		blocks[y] = append([]game.BlockType(nil), row...)
+	}
+
+	bombs := make([]*game.Bomb, 0, len(g.Bombs))
+	for _, bomb := range g.Bombs {
+		bombs = append(bombs, bomb)
+	}
+
+	powerUps := make(map[string]game.PowerUp, len(g.PowerUps))
+	for id, powerUp := range g.PowerUps {
+		powerUps[id] = powerUp
+	}
+
+	update := GameStateUpdate{
+		GameStateMeta: GameStateMeta{
+			State:              int(g.State),
+			Width:              g.Map.Width,
+			Height:             g.Map.Height,
+			InitialPlayerCount: g.InitialPlayerCount,
+			Capacity:           g.Capacity(),
+			Seed:               g.Seed,
+			Teams:              g.Settings.Teams,
+			FriendlyFire:       g.Settings.FriendlyFire,
+			Result:             g.Result,
+		},
+		Players:    players,
+		Bombs:      bombs,
+		PowerUps:   powerUps,
+		Map:        &game.GameMap{Blocks: blocks, Players: players},
+		Explosions: append([]game.TimedExplosion(nil), g.Explosions...),
+	}
+
+	if g.State == game.GameWaiting && !g.WaitingTimer.IsZero() {
+		update.LobbyJoinEndTime = g.WaitingTimer.UnixMilli()
+	}
+
+	switch g.State {
+	case game.GameCountdown:
+		remainingCountdown := int(g.CountdownTimer.Sub(now).Seconds())
+		if remainingCountdown < 0 {
+			remainingCountdown = 0
+		}
+		update.Countdown = remainingCountdown
+	case game.GameRunning:
+		update.ElapsedTime = int(now.Sub(g.StartTime).Seconds())
+		update.TimeLeft = int(math.Ceil(g.TimeLeft(now).Seconds()))
+		update.SuddenDeath = g.SuddenDeath
+		update.ClosingTiles = g.ClosingTiles(game.ClosingTilesShown)
+	}
+
+	// Marshal while still holding the lock: players and bombs are live pointers
+	snap := &stateSnapshot{
+		update:   update,
+		players:  make(map[string][]byte, len(players)),
+		bombs:    make(map[string][]byte, len(bombs)),
+		powerUps: powerUps,
+	}
+	snap.meta, _ = json.Marshal(update.GameStateMeta)
+	for _, p := range players {
+		snap.players[p.ID], _ = json.Marshal(p)
+		snap.playerOrder = append(snap.playerOrder, p.ID)
+	}
+	for _, bomb := range bombs {
+		snap.bombs[bomb.ID], _ = json.Marshal(bomb)
+	}
+	snap.explosions, _ = json.Marshal(update.Explosions)
+
+	return snap
+}
+
+// diffStates returns what changed from prev to next, and whether anything did
+func diffStates(prev, next *stateSnapshot) (GameStateDelta, bool) {
+	var delta GameStateDelta
+	changed := false
+
+	if !bytes.Equal(prev.meta, next.meta) {
+		meta := next.update.GameStateMeta
+		delta.Meta = &meta
+		changed = true
+	}
+
+	prevBlocks, nextBlocks := prev.update.Map.Blocks, next.update.Map.Blocks
+	for y := range nextBlocks {
+		for x := range nextBlocks[y] {
+			if y >= len(prevBlocks) || x >= len(prevBlocks[y]) || prevBlocks[y][x] != nextBlocks[y][x] {
+				delta.Tiles = append(delta.Tiles, TileChange{X: x, Y: y, Type: nextBlocks[y][x]})
+			}
+		}
+	}
+
+	for _, id := range next.playerOrder {
+		if !bytes.Equal(prev.players[id], next.players[id]) {
+			delta.Players = append(delta.Players, next.players[id])
+		}
+	}
+	for _, id := range prev.playerOrder {
+		if _, ok := next.players[id]; !ok {
+			delta.RemovedPlayers = append(delta.RemovedPlayers, id)
+		}
+	}
+	if !equalStrings(prev.playerOrder, next.playerOrder) {
+		delta.PlayerOrder = next.playerOrder // An order that became empty shows in RemovedPlayers
+	}
+
+	for id, data := range next.bombs {
+		if !bytes.Equal(prev.bombs[id], data) {
+			delta.Bombs = append(delta.Bombs, data)
+		}
+	}
+	for id := range prev.bombs {
+		if _, ok := next.bombs[id]; !ok {
+			delta.RemovedBombs = append(delta.RemovedBombs, id)
+		}
+	}
+
+	for id, powerUp := range next.powerUps {
+		if old, ok := prev.powerUps[id]; !ok || old != powerUp {
+			if delta.PowerUps == nil {
+				delta.PowerUps = make(map[string]game.PowerUp)
+			}
+			delta.PowerUps[id] = powerUp
+		}
+	}
+	for id := range prev.powerUps {
+		if _, ok := next.powerUps[id]; !ok {
+			delta.RemovedPowerUps = append(delta.RemovedPowerUps, id)
+		}
+	}
+
+	if !bytes.Equal(prev.explosions, next.explosions) {
+		delta.Explosions = next.explosions
+	}
+
+	changed = changed || len(delta.Tiles) > 0 || len(delta.Players) > 0 || len(delta.RemovedPlayers) > 0 ||
+		delta.PlayerOrder != nil || len(delta.Bombs) > 0 || len(delta.RemovedBombs) > 0 ||
+		len(delta.PowerUps) > 0 || len(delta.RemovedPowerUps) > 0 || delta.Explosions != nil
+	return delta, changed
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
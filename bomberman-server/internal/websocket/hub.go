@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"bomberman-server/internal/game"
+	"bomberman-server/internal/replay"
 )
 
 var loggedOnce = false
@@ -30,32 +31,86 @@ type Hub struct {
 	// Game instance
 	game *game.Game
 
+	// Connection limits for clients of this hub
+	settings Settings
+
+	// Last broadcast game state and its sequence number, for delta updates
+	lastState *stateSnapshot
+	stateSeq  uint64
+
+	// Set for hubs streaming a replay instead of a live game, see NewReplayHub
+	replay           *replay.Playback
+	lastReplayStatus *replay.Status
+
+	// Closed by Stop to shut the hub down
+	quit     chan struct{}
+	stopOnce sync.Once
+
 	// Mutex for protecting client operations
 	mutex sync.RWMutex
 }
 
 // NewHub creates a new hub with a game instance
-func NewHub(game *game.Game) *Hub {
+func NewHub(game *game.Game, settings Settings) *Hub {
 	return &Hub{
 		Broadcast:  make(chan []byte),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		clients:    make(map[*Client]bool),
 		game:       game,
+		settings:   settings,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Stop shuts down the hub's run loop and disconnects all of its clients.
+func (h *Hub) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.quit)
+	})
+}
+
+// RegisterClient hands a client to the hub. It returns false if the hub has been stopped.
+func (h *Hub) RegisterClient(client *Client) bool {
+	select {
+	case h.Register <- client:
+		return true
+	case <-h.quit:
+		return false
+	}
+}
+
+// closeAllClients disconnects every client when the hub shuts down
+func (h *Hub) closeAllClients() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.clients {
+		client.closeSend()
+		delete(h.clients, client)
 	}
 }
 
 // Run starts the hub and handles messages
 func (h *Hub) Run() {
-	ticker := time.NewTicker(50 * time.Millisecond) // 20 updates per second
+	ticker := time.NewTicker(h.settings.BroadcastInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
+		case <-h.quit:
+			h.closeAllClients()
+			return
+
 		case client := <-h.Register:
+			client.requestSnapshot() // New clients start from a full snapshot
 			h.mutex.Lock()
 			h.clients[client] = true
 			h.mutex.Unlock()
 			log.Println("New client connected")
+			if h.replay != nil {
+				h.sendReplayInfo(client)
+			}
 
 			// Broadcast updated player count after registration
 			h.mutex.RLock()
@@ -71,13 +126,13 @@ func (h *Hub) Run() {
 			h.mutex.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.Send)
+				client.closeSend()
 
-				if client.ID != "" {
-					log.Printf("Player %s disconnected", client.ID)
+				if playerID := client.playerID(); playerID != "" {
+					log.Printf("Player %s disconnected", playerID)
 					// TODO: Handle player disconnect in the game
 					// Add this line:
-					h.game.HandlePlayerDisconnect(client.ID) // Notify the game logic
+					h.game.HandlePlayerDisconnect(playerID) // Notify the game logic
 				}
 			}
 			h.mutex.Unlock()
@@ -94,16 +149,26 @@ func (h *Hub) Run() {
 			})
 			h.broadcastMessage(playerCountMsg)
 
+			// Nobody is watching the replay any more
+			if h.replay != nil && count == 0 {
+				h.replay.Stop()
+				h.Stop()
+			}
+
 		case message := <-h.Broadcast:
 			h.broadcastMessage(message)
 
 		case <-ticker.C:
-			h.game.Update()
+			// The game advances on its own loop; the hub only publishes it
 			if !loggedOnce {
 				log.Printf("Sending GameState. Map nil? %v", h.game.Map == nil)
 				loggedOnce = true
 			}
+			h.SendGameEvents()
 			h.SendGameState()
+			if h.replay != nil {
+				h.sendReplayStatus()
+			}
 		}
 	}
 }
@@ -117,51 +182,120 @@ func (h *Hub) broadcastMessage(message []byte) {
 		select {
 		case client.Send <- message:
 		default:
-			close(client.Send)
+			client.closeSend()
 			delete(h.clients, client)
 		}
 	}
 }
 
-// SendGameState sends the current game state to all connected clients
+// SendGameState sends the game state to all connected clients: a full snapshot to
+// clients that just joined or asked for a resync, and only what changed to the rest.
+// Every change bumps the sequence number so clients can detect a missed update.
 func (h *Hub) SendGameState() {
-	// Create game state update message
-	update := GameStateUpdate{
-		State:      int(h.game.State),
-		Players:    h.game.PlayersInSlotOrder(), // Use the ordered list of players
-		Bombs:      h.game.GetBombList(),
-		PowerUps:   h.game.PowerUps,
-		Map:        h.game.Map,
-		Explosions: h.game.Explosions,
+	snap := h.captureState()
+
+	var deltaMessage []byte
+	if h.lastState != nil {
+		delta, changed := diffStates(h.lastState, snap)
+		if changed {
+			h.stateSeq++
+			message, err := json.Marshal(map[string]interface{}{
+				"type":    "gameStateDelta",
+				"seq":     h.stateSeq,
+				"baseSeq": h.stateSeq - 1,
+				"delta":   delta,
+			})
+			if err != nil {
+				log.Println("Error marshaling game state delta:", err)
+				return
+			}
+			deltaMessage = message
+		}
+	} else {
+		h.stateSeq++
 	}
+	h.lastState = snap
+
+	var fullMessage []byte
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
 
-	if h.game.State == game.GameWaiting && !h.game.WaitingTimer.IsZero() {
-		update.LobbyJoinEndTime = h.game.WaitingTimer.UnixMilli()
+	for client := range h.clients {
+		if client.takeSnapshotRequest() {
+			if fullMessage == nil {
+				// Convert to JSON
+				message, err := json.Marshal(map[string]interface{}{
+					"type":  "gameState",
+					"seq":   h.stateSeq,
+					"state": snap.update,
+				})
+				if err != nil {
+					log.Println("Error marshaling game state:", err)
+					return
+				}
+				fullMessage = message
+			}
+			client.trySend(fullMessage)
+		} else if deltaMessage != nil {
+			// A dropped delta shows up as a sequence gap and the client asks for a resync
+			client.trySend(deltaMessage)
+		}
 	}
+}
 
-	switch h.game.State {
-	case game.GameCountdown:
-		remainingCountdown := int(time.Until(h.game.CountdownTimer).Seconds())
-		if remainingCountdown < 0 {
-			remainingCountdown = 0
+// SendGameEvents broadcasts the events the game produced since the last tick
+func (h *Hub) SendGameEvents() {
+	var events []game.Event
+	if h.replay != nil {
+		events = h.replay.DrainEvents() // Never interleaves with a seek
+	} else {
+		events = h.game.DrainEvents()
+	}
+
+	for _, event := range events {
+		msg := Message{
+			Type:    event.Type,
+			Payload: mustMarshal(event.Payload),
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			log.Printf("Error marshaling %s event: %v", event.Type, err)
+			continue
+		}
+		if event.PlayerID != "" {
+			h.sendToPlayer(event.PlayerID, data)
+			continue
+		}
+		h.broadcastMessage(data)
+
+		if elimination, ok := event.Payload.(game.EliminationEvent); ok {
+			h.makeSpectators(elimination.PlayerID)
 		}
-		update.Countdown = remainingCountdown
-	case game.GameRunning:
-		update.ElapsedTime = int(time.Since(h.game.StartTime).Seconds())
 	}
+}
 
-	// Convert to JSON
-	message, err := json.Marshal(map[string]interface{}{
-		"type":  "gameState",
-		"state": update,
-	})
+// sendToPlayer sends a message to every connection bound to the player
+func (h *Hub) sendToPlayer(playerID string, data []byte) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
 
-	if err != nil {
-		log.Println("Error marshaling game state:", err)
-		return
+	for client := range h.clients {
+		if client.playerID() == playerID {
+			client.trySend(data)
+		}
 	}
+}
 
-	h.broadcastMessage(message)
+// makeSpectators turns every connection bound to the player into a spectator feed
+func (h *Hub) makeSpectators(playerID string) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients {
+		if client.playerID() == playerID {
+			client.becomeSpectator()
+		}
+	}
 }
 
 // BroadcastPlayerJoined sends a message to all clients that a player has joined.
@@ -189,8 +323,8 @@ func (h *Hub) SendChatMessage(playerID, clientProvidedNickname, message string)
 
 	h.game.Mutex.RLock()
 	if p, ok := h.game.Players[playerID]; ok {
-		authoritativeNickname = p.Nickname // Use nickname from game state
-		authoritativePlayerNumber = p.Number   // Use number from game state
+		authoritativeNickname = p.Nickname   // Use nickname from game state
+		authoritativePlayerNumber = p.Number // Use number from game state
 		foundInGame = true
 	}
 	h.game.Mutex.RUnlock()
@@ -225,18 +359,15 @@ func mustMarshal(v interface{}) json.RawMessage {
 	return b
 }
 
-// HandlePlayerAction processes player actions
-func (h *Hub) HandlePlayerAction(action PlayerAction) {
-	switch action.Action {
-	case "move_up":
-		h.game.MovePlayer(action.PlayerID, 0, -1)
-	case "move_down":
-		h.game.MovePlayer(action.PlayerID, 0, 1)
-	case "move_left":
-		h.game.MovePlayer(action.PlayerID, -1, 0)
-	case "move_right":
-		h.game.MovePlayer(action.PlayerID, 1, 0)
-	case "place_bomb":
-		h.game.PlaceBomb(action.PlayerID)
+// HandlePlayerAction queues a player action sent by client. The game applies it at the
+// start of its next tick; move results come back to the player as move_result events.
+func (h *Hub) HandlePlayerAction(client *Client, action PlayerAction) {
+	err := h.game.QueueInput(game.Input{
+		PlayerID: action.PlayerID,
+		Seq:      action.Seq,
+		Action:   action.Action,
+	})
+	if err != nil {
+		client.sendError("action_error", err.Error())
 	}
 }
@@ -16,6 +16,7 @@ type Message struct {
 type PlayerAction struct {
 	PlayerID string `json:"playerId"`
 	Action   string `json:"action"`
+	Seq      uint64 `json:"seq,omitempty"` // Client-side sequence number, echoed back as lastInputSeq
 	X        int    `json:"x,omitempty"`
 	Y        int    `json:"y,omitempty"`
 }
@@ -26,16 +27,54 @@ type ChatMessage struct {
 	Message  string `json:"message"`
 }
 
-// GameStateUpdate represents the current state of the game
+// GameStateMeta holds the scalar part of the game state (phase, timers, result)
+type GameStateMeta struct {
+	State              int               `json:"state"`
+	Width              int               `json:"width"` // Map size in tiles; the blocks are resized when it changes
+	Height             int               `json:"height"`
+	Countdown          int               `json:"countdown,omitempty"`
+	ElapsedTime        int               `json:"elapsedTime,omitempty"`
+	LobbyJoinEndTime   int64             `json:"lobbyJoinEndTime,omitempty"`   // Unix timestamp (milliseconds)
+	InitialPlayerCount int               `json:"initialPlayerCount,omitempty"` // Number of players at game start
+	Capacity           int               `json:"capacity"`                     // Number of players the lobby takes
+	Seed               int64             `json:"seed"`                         // Random seed of the current round
+	Teams              int               `json:"teams,omitempty"`              // Number of teams, 0 in free-for-all; players carry their team
+	FriendlyFire       bool              `json:"friendlyFire,omitempty"`       // Whether teammates' bombs hurt
+	TimeLeft           int               `json:"timeLeft,omitempty"`           // Seconds until sudden death, then until the match times out
+	SuddenDeath        bool              `json:"suddenDeath,omitempty"`        // The arena is closing
+	ClosingTiles       []game.Position   `json:"closingTiles,omitempty"`       // Next tiles sudden death closes, in order
+	Result             *game.MatchResult `json:"result,omitempty"`             // Set once the match has finished
+}
+
+// GameStateUpdate represents the current state of the game. It is sent as a full
+// "gameState" snapshot on join and on resync; other ticks send a GameStateDelta.
 type GameStateUpdate struct {
-	State              int                     `json:"state"`
-	Players            []*game.Player          `json:"players"`
-	Bombs              []*game.Bomb            `json:"bombs"`
-	PowerUps           map[string]game.PowerUp `json:"powerUps"`
-	Map                *game.GameMap           `json:"map"`
-	Explosions         []game.TimedExplosion   `json:"explosions"` // ✅ add this field
-	Countdown          int                     `json:"countdown,omitempty"`
-	ElapsedTime        int                     `json:"elapsedTime,omitempty"`
-	LobbyJoinEndTime   int64                   `json:"lobbyJoinEndTime,omitempty"`   // Unix timestamp (milliseconds)
-	InitialPlayerCount int                     `json:"initialPlayerCount,omitempty"` // Number of players at game start
+	GameStateMeta
+	Players    []*game.Player          `json:"players"`
+	Bombs      []*game.Bomb            `json:"bombs"`
+	PowerUps   map[string]game.PowerUp `json:"powerUps"`
+	Map        *game.GameMap           `json:"map"`
+	Explosions []game.TimedExplosion   `json:"explosions"` // ✅ add this field
+}
+
+// TileChange is a single map tile whose block type changed
+type TileChange struct {
+	X    int            `json:"x"`
+	Y    int            `json:"y"`
+	Type game.BlockType `json:"type"`
+}
+
+// GameStateDelta is what changed since the previous sequence number.
+// Empty fields mean "unchanged"; players and bombs are sent whole when any field changed.
+type GameStateDelta struct {
+	Meta            *GameStateMeta          `json:"meta,omitempty"` // Replaces all meta fields when present
+	Tiles           []TileChange            `json:"tiles,omitempty"`
+	Players         []json.RawMessage       `json:"players,omitempty"` // Added or changed players
+	RemovedPlayers  []string                `json:"removedPlayers,omitempty"`
+	PlayerOrder     []string                `json:"playerOrder,omitempty"` // Player IDs in slot order, when it changed
+	Bombs           []json.RawMessage       `json:"bombs,omitempty"`       // Added or changed bombs
+	RemovedBombs    []string                `json:"removedBombs,omitempty"`
+	PowerUps        map[string]game.PowerUp `json:"powerUps,omitempty"` // Added power-ups
+	RemovedPowerUps []string                `json:"removedPowerUps,omitempty"`
+	Explosions      json.RawMessage         `json:"explosions,omitempty"` // Full list, whenever it changed
 }
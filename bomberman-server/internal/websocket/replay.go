@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"bomberman-server/internal/replay"
+)
+
+// ReplayControl is the payload of a replay_control message. Fields left out are unchanged.
+type ReplayControl struct {
+	Speed  float64 `json:"speed,omitempty"`  // Playback rate, e.g. 1 or 2
+	Paused *bool   `json:"paused,omitempty"` // Pause or resume
+	SeekMs *int64  `json:"seekMs,omitempty"` // Jump to this many milliseconds into the match
+}
+
+// NewReplayHub creates a hub that streams a playback to spectators. It uses the same
+// state messages as a live room, so the game client can render it unchanged, but
+// clients cannot join or act; they can only steer playback with replay_control.
+// The hub stops itself and the playback when its last client leaves.
+func NewReplayHub(playback *replay.Playback, settings Settings) *Hub {
+	h := NewHub(playback.Game(), settings)
+	h.replay = playback
+	return h
+}
+
+// sendReplayInfo tells a newly connected client which match it is watching
+func (h *Hub) sendReplayInfo(client *Client) {
+	msg := Message{
+		Type:    "replay_info",
+		Payload: mustMarshal(replay.Summarize(h.replay.Recording())),
+	}
+	if data, err := json.Marshal(msg); err == nil {
+		client.trySend(data)
+	}
+}
+
+// sendReplayStatus broadcasts the playback position whenever it changed
+func (h *Hub) sendReplayStatus() {
+	status := h.replay.Status()
+	if h.lastReplayStatus != nil && *h.lastReplayStatus == status {
+		return
+	}
+	h.lastReplayStatus = &status
+
+	msg := Message{
+		Type:    "replay_status",
+		Payload: mustMarshal(status),
+	}
+	if data, err := json.Marshal(msg); err == nil {
+		h.broadcastMessage(data)
+	}
+}
+
+// handleReplayMessage handles a message from a client of a replay hub
+func (c *Client) handleReplayMessage(message Message) {
+	switch message.Type {
+	case "resync":
+		c.requestSnapshot()
+
+	case "replay_control":
+		var control ReplayControl
+		if err := json.Unmarshal(message.Payload, &control); err != nil {
+			c.sendError("replay_error", "invalid replay_control payload")
+			return
+		}
+		playback := c.Hub.replay
+		if control.Speed != 0 {
+			if err := playback.SetSpeed(control.Speed); err != nil {
+				c.sendError("replay_error", err.Error())
+				return
+			}
+		}
+		if control.Paused != nil {
+			playback.SetPaused(*control.Paused)
+		}
+		if control.SeekMs != nil {
+			if err := playback.Seek(time.Duration(*control.SeekMs) * time.Millisecond); err != nil {
+				log.Printf("Replay seek failed: %v", err)
+				c.sendError("replay_error", err.Error())
+				return
+			}
+		}
+
+	default:
+		c.sendError("replay_error", "replays are read-only")
+	}
+}
@@ -0,0 +1,25 @@
+package websocket
+
+import "time"
+
+// Settings holds the connection limits applied to every client of a hub.
+type Settings struct {
+	PingInterval      time.Duration // How often the server pings each client
+	MaxMessageSize    int64         // Largest message accepted from a client, in bytes
+	BroadcastInterval time.Duration // How often the game state is sent to clients
+}
+
+// DefaultSettings returns the limits used before configuration was introduced.
+func DefaultSettings() Settings {
+	return Settings{
+		PingInterval:      (60 * time.Second * 9) / 10,
+		MaxMessageSize:    512,
+		BroadcastInterval: 50 * time.Millisecond, // 20 updates per second
+	}
+}
+
+// pongWait is how long a client may stay silent before it is dropped.
+// It is kept slightly longer than the ping interval so a pong can arrive in time.
+func (s Settings) pongWait() time.Duration {
+	return (s.PingInterval * 10) / 9
+}
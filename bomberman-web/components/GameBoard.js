@@ -1,7 +1,7 @@
 // GameBoard component extracted from game.js
 import { Tile } from './Tile.js';
 import { h } from '../framework/index.js';
-export function GameBoard({ map, players, selfId, countdown, bombs, deadPlayers, isGameFinished, winner, onPlayAgain, localPlayerFrame, helpers, gameStartCountdownTime }) {
+export function GameBoard({ map, players, selfId, countdown, bombs, deadPlayers, isGameFinished, winner, onPlayAgain, localPlayerFrame, helpers, gameStartCountdownTime, timeLeft = 0, suddenDeath = false, closingTiles = [] }) {
     const { SPRITES, SPRITE_FRAMES, BLOCK_WALL, BLOCK_DESTRUCTIBLE, BLOCK_INDESTRUCTIBLE, TILE_SIZE, renderBombSprite, renderFlameSprite, renderPowerUpSprite, renderDeathSprite, getFlameType, BOMB_WIDTH, BOMB_HEIGHT, FLAME_WIDTH, FLAME_HEIGHT, DEATH_WIDTH, DEATH_HEIGHT } = helpers;
 
     // Try different ways to access powerUps
@@ -25,6 +25,14 @@ export function GameBoard({ map, players, selfId, countdown, bombs, deadPlayers,
                 }, `Game starts in ${gameStartCountdownTime}`)
             ] : []),
             
+            // Match clock: time until sudden death, then until the arena is closed
+            ...(timeLeft > 0 && !isGameFinished ? [
+                h('div', {
+                    style: `position:absolute;left:0;top:-28px;width:100%;z-index:10;line-height:24px;
+                            text-align:center;font-weight:bold;color:${suddenDeath ? '#ff4444' : '#fff'};`
+                }, `${suddenDeath ? 'SUDDEN DEATH' : 'Sudden death in'} ${Math.floor(timeLeft / 60)}:${String(timeLeft % 60).padStart(2, '0')}`)
+            ] : []),
+
             // 🔲 Render map tiles
             map.blocks.map((row, y) =>
                 h('div', { style: 'display: flex;' },
@@ -36,6 +44,14 @@ export function GameBoard({ map, players, selfId, countdown, bombs, deadPlayers,
                 )
             ),
 
+            // Tiles sudden death closes next, the first one brightest
+            ...closingTiles.map((pos, i) => h('div', {
+                key: `closing-${pos.x}-${pos.y}`,
+                style: `position:absolute;left:${pos.x * TILE_SIZE}px;top:${pos.y * TILE_SIZE}px;
+                        width:${TILE_SIZE}px;height:${TILE_SIZE}px;z-index:3;pointer-events:none;
+                        background:rgba(255,0,0,${0.6 - i * 0.06});`
+            })),
+
             // ADDED: Direct player rendering loop
             ...(players.flatMap((player) => {
                 const pos = player.position || player.Position;
@@ -62,7 +78,7 @@ export function GameBoard({ map, players, selfId, countdown, bombs, deadPlayers,
                     top:${pos.y * TILE_SIZE - 48}px;
                     width:${frameWidth}px;
                     height:${frameHeight}px;
-                    background: url(${SPRITES.players[number - 1]}) no-repeat;
+                    background: url(${SPRITES.players[(number - 1) % SPRITES.players.length]}) no-repeat;
                     background-position: -${frame * frameWidth}px -${row * frameHeight}px;
                     background-size: ${frameWidth * SPRITE_FRAMES}px ${frameHeight * 4}px;
                     z-index:4;
@@ -178,7 +194,7 @@ export function GameBoard({ map, players, selfId, countdown, bombs, deadPlayers,
                         height: ${TILE_SIZE}px;
                         z-index: 3;
                     `
-                }, h(sprite.tag, sprite.attrs));
+                }, h(sprite.tag, sprite.attrs, sprite.children || []));
             }).filter(Boolean)),
 
             // Commented out: Players are already rendered by Tile components (this comment is now misleading, live players are rendered above)
@@ -18,6 +18,8 @@ function renderLobby(root, { initialNickname, onSendChat, gameInProgress }) { //
         <div id="player-count">Players: ${playerCount}/4</div>
         <div id="lobby-status">${gameInProgress ? 'Game in progress. Please wait...' : 'Connected to lobby. Waiting for players...'}</div>
         <div id="lobby-countdown" style="margin-top: 10px; font-weight: bold;"></div>
+        <div id="team-picker" style="margin-top: 10px;"></div>
+        <div id="bot-filler" style="margin-top: 10px;"></div>
         <div id="chat-area" style="margin-top:16px;max-height:120px;overflow-y:auto;background:#222;padding:8px;border-radius:4px;"></div>
         <input id="chat-input" type="text" placeholder="Type a message..." style="width:70%;" /> <!-- Enabled by default -->
         <button id="chat-send">Send</button> <!-- Enabled by default -->
@@ -50,6 +52,54 @@ function updatePlayerCount(count) {
     if (el) el.textContent = `Players: ${playerCount}/4`;
 }
 
+// Shows one button per team in team mode, the player's own team highlighted.
+// Picking a team sends a team message; the next state shows whether it was accepted.
+function updateTeams({ teams, players, selfId, onPick }) {
+    const pickerEl = document.getElementById('team-picker');
+    if (!pickerEl) return;
+    pickerEl.innerHTML = '';
+    if (!teams) return;
+
+    const self = (players || []).find(p => p.id === selfId);
+    for (let team = 1; team <= teams; team++) {
+        const members = (players || []).filter(p => p.team === team).map(p => p.nickname);
+        const button = document.createElement('button');
+        button.textContent = `Team ${team} (${members.join(', ') || 'empty'})`;
+        button.style.margin = '0 4px';
+        if (self && self.team === team) {
+            button.style.fontWeight = 'bold';
+            button.disabled = true;
+        }
+        button.onclick = () => onPick(team);
+        pickerEl.appendChild(button);
+    }
+}
+
+// Offers to fill the free slots of the lobby with bots of a chosen difficulty, so a
+// single player can start a match. Hidden once the lobby is full.
+function updateBots({ players, capacity, onFill }) {
+    const fillerEl = document.getElementById('bot-filler');
+    if (!fillerEl) return;
+    fillerEl.innerHTML = '';
+    const free = capacity - (players || []).length;
+    if (free <= 0) return;
+
+    const select = document.createElement('select');
+    for (const difficulty of ['easy', 'normal', 'hard']) {
+        const option = document.createElement('option');
+        option.value = difficulty;
+        option.textContent = difficulty;
+        option.selected = difficulty === 'normal';
+        select.appendChild(option);
+    }
+    const button = document.createElement('button');
+    button.textContent = `Fill ${free} empty slot${free === 1 ? '' : 's'} with bots`;
+    button.style.margin = '0 4px';
+    button.onclick = () => onFill(select.value);
+    fillerEl.appendChild(button);
+    fillerEl.appendChild(select);
+}
+
 // Modified to handle system messages (like player join announcements)
 function appendChatMessage({ playerName, message, playerNumber, isSystem = false }) {
     const chatArea = document.getElementById('chat-area');
@@ -89,4 +139,4 @@ function clearLobbyCountdown() {
 }
 
 // Removed lobbyCountdownInterval from export
-export { renderLobby, updatePlayerCount, appendChatMessage, updateLobbyCountdownDisplay, clearLobbyCountdown };
+export { renderLobby, updatePlayerCount, updateTeams, updateBots, appendChatMessage, updateLobbyCountdownDisplay, clearLobbyCountdown };
@@ -144,6 +144,9 @@ export function renderGame(root, gameState, selfId, localPlayerFrame = 0, onPlay
                     selfId,
                     localPlayerFrame, // Pass localPlayerFrame as a separate prop
                     countdown: serverGame.countdown, // Pass the raw countdown from server state
+                    timeLeft: serverGame.timeLeft || 0,
+                    suddenDeath: !!serverGame.suddenDeath,
+                    closingTiles: serverGame.closingTiles || [],
                     bombs: serverGame.bombs || [], // Use serverGame here
                     deadPlayers,
                     isGameFinished, // Pass calculated isGameFinished
@@ -1,7 +1,7 @@
-import { renderLobby, updatePlayerCount, appendChatMessage, updateLobbyCountdownDisplay, clearLobbyCountdown } from './components/Lobby.js';
+import { renderLobby, updatePlayerCount, updateTeams, updateBots, appendChatMessage, updateLobbyCountdownDisplay, clearLobbyCountdown } from './components/Lobby.js';
 import { removeStatsBar, updatePlayerStats } from './components/PlayerStats.js'; // Ensure this import is correct
 import { showDeathMessage, handleGameEnd } from './components/Overlays.js';
-import { connectWebSocket, socket, isJoined } from './ws.js';
+import { connectWebSocket, connectReplay, sendReplayControl, socket, isJoined, currentRoomId, currentReplayId, nextInputSeq } from './ws.js';
 import { renderGame } from './game.js';
 
 // Add a gameState variable to track if a game is in progress
@@ -50,7 +50,7 @@ async function joinGame(nickname) {
     const res = await fetch('http://localhost:8080/api/game/join', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ nickname })
+        body: JSON.stringify({ nickname, roomId: currentRoomId() })
     });
     const data = await res.json();
     if (res.status !== 200) {
@@ -79,6 +79,7 @@ async function joinGame(nickname) {
         }
         return;
     }
+    localStorage.setItem('bomberman_sessionToken', data.sessionToken);
     return data.playerID;
 }
 
@@ -114,7 +115,7 @@ function connectPlayer(nickname) {
     joinGame(nickname).then(playerID => {
         if (playerID) {
             currentPlayerID = playerID;
-            // Store details in localStorage
+            // Store details in localStorage (the session token was stored by joinGame)
             localStorage.setItem('bomberman_currentPlayerID', currentPlayerID);
             localStorage.setItem('bomberman_currentNickname', currentNickname);
             console.log(`Stored session for ${currentNickname} (${currentPlayerID})`);
@@ -124,6 +125,7 @@ function connectPlayer(nickname) {
             // Clear any stored credentials if join fails definitively
             localStorage.removeItem('bomberman_currentPlayerID');
             localStorage.removeItem('bomberman_currentNickname');
+            localStorage.removeItem('bomberman_sessionToken');
             // Potentially re-render lobby or show error
             startLobby(); // This will re-render lobby without prefilled/restored session
         }
@@ -131,6 +133,7 @@ function connectPlayer(nickname) {
         console.error("Error joining game:", error);
         localStorage.removeItem('bomberman_currentPlayerID');
         localStorage.removeItem('bomberman_currentNickname');
+        localStorage.removeItem('bomberman_sessionToken');
         startLobby();
     });
     
@@ -192,7 +195,7 @@ function startLobby() {
         connectWebSocket(currentNickname, currentPlayerID, handleWSMessage);
     } else {
         console.error("[startLobby] CRITICAL: No playerID or nickname. Redirecting to register.");
-        window.location.href = 'register.html';
+        window.location.href = 'register.html' + window.location.search;
         return;
     }
     
@@ -239,7 +242,10 @@ function requestGameRestart() {
 }
 
 function handleWSMessage(data) {
-    if (data.type === 'player_count') {
+    if (data.type === 'join_ack') {
+        // The server is authoritative for our player ID (it changes after a game reset)
+        currentPlayerID = data.payload.playerId;
+    } else if (data.type === 'player_count') {
         updatePlayerCount(data.count);
     } else if (data.type === 'chat_message' || data.type === 'chat') { // Handle both potential chat message types
         let payload = data.payload;
@@ -322,6 +328,35 @@ function handleWSMessage(data) {
                     },
                     gameInProgress // Pass the updated gameInProgress
                 });
+                updateTeams({
+                    teams: data.state.teams || 0,
+                    players: data.state.players,
+                    selfId: currentPlayerID,
+                    onPick: (team) => {
+                        if (!isJoined()) return;
+                        if (socket && socket.readyState === WebSocket.OPEN) {
+                            socket.send(JSON.stringify({
+                                type: 'team',
+                                playerId: currentPlayerID,
+                                payload: { team }
+                            }));
+                        }
+                    }
+                });
+                updateBots({
+                    players: data.state.players,
+                    capacity: data.state.capacity || 4,
+                    onFill: (difficulty) => {
+                        if (!isJoined()) return;
+                        if (socket && socket.readyState === WebSocket.OPEN) {
+                            socket.send(JSON.stringify({
+                                type: 'fill_bots',
+                                playerId: currentPlayerID,
+                                payload: { difficulty }
+                            }));
+                        }
+                    }
+                });
             }
              // Handle lobby countdown from gameState if applicable
             if (data.state.lobbyJoinEndTime && data.state.lobbyJoinEndTime > 0) {
@@ -369,6 +404,10 @@ function handleWSMessage(data) {
             playerNumber: data.payload.playerNumber 
             // isSystem will be false/undefined by default
         });
+    } else if (data.type === 'team_error') {
+        appendChatMessage({ message: `Team change refused: ${data.payload.error}`, isSystem: true });
+    } else if (data.type === 'bots_error') {
+        appendChatMessage({ message: `Adding bots refused: ${data.payload.error}`, isSystem: true });
     // ... (other message types) ...
     }
     // Ensure all other calls to renderLobby in this file are updated:
@@ -608,7 +647,7 @@ function handleKeyInput(e, isDown) {
         // Still prevent default for game keys to avoid browser actions
         if ([
             'ArrowUp', 'ArrowDown', 'ArrowLeft', 'ArrowRight',
-            'w', 'a', 's', 'd', ' ', 'Enter'
+            'w', 'a', 's', 'd', 'q', 'e', ' ', 'Enter'
         ].includes(e.key)) {
             e.preventDefault();
         }
@@ -635,6 +674,13 @@ function handleKeyInput(e, isDown) {
                 lastBombTime = now;
             }
         }
+        // Punch the bomb in front (Q) and set off remote bombs (E)
+        if (e.key === 'q' && !e.repeat) {
+            sendAction('punch');
+        }
+        if (e.key === 'e' && !e.repeat) {
+            sendAction('detonate');
+        }
     } else {
         delete keysPressed[e.key];
         delete keyPressTime[e.key];
@@ -643,7 +689,7 @@ function handleKeyInput(e, isDown) {
     // Prevent default for movement/bomb keys
     if ([
         'ArrowUp', 'ArrowDown', 'ArrowLeft', 'ArrowRight',
-        'w', 'a', 's', 'd', ' ', 'Enter'
+        'w', 'a', 's', 'd', 'q', 'e', ' ', 'Enter'
     ].includes(e.key)) {
         e.preventDefault();
     }
@@ -661,15 +707,88 @@ function sendAction(action) {
         return; // Skip sending actions for dead players
     }
     
+    // Numbered so the server can apply actions in order and echo back the last one it applied
     socket.send(JSON.stringify({
         type: 'action',
         playerId: currentPlayerID,
-        payload: { playerId: currentPlayerID, action }
+        payload: { playerId: currentPlayerID, action, seq: nextInputSeq() }
     }));
 }
 
+// --- Replay viewer (index.html?replay=<id>) ---
+const REPLAY_SEEK_STEP_MS = 5000;
+let replayStatus = null;
+
+// Shows a recorded match. Space pauses, 1/2 pick the speed, arrows seek and Home restarts.
+function startReplay(replayId) {
+    root.innerHTML = '';
+    const gameRoot = document.createElement('div');
+    gameRoot.id = 'game-root';
+    root.appendChild(gameRoot);
+    const replayBar = document.createElement('div');
+    replayBar.id = 'replay-bar';
+    replayBar.style.cssText = 'position:fixed;bottom:10px;left:50%;transform:translateX(-50%);padding:6px 12px;background:rgba(30,30,40,0.9);border-radius:5px;font-size:0.9em;';
+    replayBar.textContent = 'Loading replay...';
+    root.appendChild(replayBar);
+
+    window.addEventListener('keydown', onReplayKeyDown, false);
+    connectReplay(replayId, handleReplayMessage);
+}
+
+function handleReplayMessage(data) {
+    if (data.type === 'gameState') {
+        gameState = data;
+        updatePlayersStatsFixed(data);
+        const gameRoot = document.getElementById('game-root');
+        if (gameRoot) {
+            // "Play again" on the end screen watches the match again
+            renderGame(gameRoot, data, null, 0, () => sendReplayControl({ seekMs: 0, paused: false }));
+        }
+    } else if (data.type === 'replay_status') {
+        replayStatus = data.payload;
+        updateReplayBar();
+    } else if (data.type === 'replay_error') {
+        console.warn("Replay control rejected:", data.payload.error);
+    }
+}
+
+function updateReplayBar() {
+    const bar = document.getElementById('replay-bar');
+    if (!bar || !replayStatus) return;
+    const seconds = (ms) => (ms / 1000).toFixed(1);
+    const state = replayStatus.finished ? 'finished' : (replayStatus.paused ? 'paused' : `${replayStatus.speed}x`);
+    bar.textContent = `Replay ${seconds(replayStatus.positionMs)}s / ${seconds(replayStatus.durationMs)}s (${state}) - Space: pause, 1/2: speed, ←/→: seek, Home: restart`;
+}
+
+function onReplayKeyDown(e) {
+    if (!replayStatus) return;
+    const seekTo = (ms) => sendReplayControl({ seekMs: Math.max(0, Math.min(ms, replayStatus.durationMs)) });
+
+    if (e.key === ' ') {
+        sendReplayControl({ paused: !replayStatus.paused });
+    } else if (e.key === '1' || e.key === '2') {
+        sendReplayControl({ speed: Number(e.key), paused: false });
+    } else if (e.key === 'ArrowLeft') {
+        seekTo(replayStatus.positionMs - REPLAY_SEEK_STEP_MS);
+    } else if (e.key === 'ArrowRight') {
+        seekTo(replayStatus.positionMs + REPLAY_SEEK_STEP_MS);
+    } else if (e.key === 'Home') {
+        seekTo(0);
+    } else {
+        return;
+    }
+    e.preventDefault();
+}
+
 // Ensure this main logic runs after the script is parsed, preferably on DOMContentLoaded
 function initializeApp() {
+    // Watching a replay needs no player
+    const replayId = currentReplayId();
+    if (replayId) {
+        startReplay(replayId);
+        return;
+    }
+
     currentPlayerID = localStorage.getItem('bomberman_currentPlayerID');
     currentNickname = localStorage.getItem('bomberman_currentNickname');
 
@@ -677,7 +796,7 @@ function initializeApp() {
 
     if (!currentPlayerID && !onRegisterPage) {
         // No player ID and not on registration page, redirect to register
-        window.location.href = 'register.html';
+        window.location.href = 'register.html' + window.location.search;
         return; // Stop further execution of index.js for this path
     }
 
@@ -693,7 +812,7 @@ function initializeApp() {
         // This case should ideally not be hit if the redirect above works.
         // As a fallback, redirect again.
         console.warn("PlayerID missing on index.html, redirecting to register.html as a fallback.");
-        window.location.href = 'register.html';
+        window.location.href = 'register.html' + window.location.search;
         return;
     }
     
@@ -4,7 +4,42 @@ export const POWER_UP_IMAGES = {
     flame: './aseets/sprites/flamepower.png',
 };
 
+// Power-ups without a sprite yet are drawn as a labelled token
+const POWER_UP_LABELS = {
+    kick: 'K',
+    punch: 'P',
+    remote: 'R',
+    pierce: 'X',
+    fullfire: 'F',
+    skull: '☠',
+};
+
 export function renderPowerUpSprite({ type }) {
+    if (!POWER_UP_IMAGES[type] && POWER_UP_LABELS[type]) {
+        return {
+            tag: 'div',
+            attrs: {
+                title: type,
+                style: `
+                position: absolute;
+                top: 50%;
+                left: 50%;
+                transform: translate(-50%, -50%);
+                width: 30px;
+                height: 30px;
+                line-height: 30px;
+                border-radius: 50%;
+                background: ${type === 'skull' ? '#5a2a6e' : '#2a5a8e'};
+                color: #fff;
+                font-weight: bold;
+                text-align: center;
+                pointer-events: none;
+                user-select: none;
+            `
+            },
+            children: [POWER_UP_LABELS[type]]
+        };
+    }
     return {
         tag: 'img',
         attrs: {
@@ -17,7 +17,7 @@ async function handleRegistration() {
         const res = await fetch('http://localhost:8080/api/game/join', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ nickname })
+            body: JSON.stringify({ nickname, roomId: new URLSearchParams(window.location.search).get('room') || '' })
         });
 
         const data = await res.json();
@@ -25,7 +25,8 @@ async function handleRegistration() {
         if (res.status === 200 && data.playerID) {
             localStorage.setItem('bomberman_currentPlayerID', data.playerID);
             localStorage.setItem('bomberman_currentNickname', nickname);
-            window.location.href = 'index.html'; // Redirect to lobby/game page
+            localStorage.setItem('bomberman_sessionToken', data.sessionToken);
+            window.location.href = 'index.html' + window.location.search; // Redirect to lobby/game page, keeping ?room=
         } else {
             errorMessageDiv.textContent = data.error || 'Failed to register. Please try again.';
             registerButton.disabled = false;
@@ -1,5 +1,124 @@
 let socket = null; // Ensure socket is declared at the module level, initialized to null
 let hasJoined = false;
+let inputSeq = 0; // Sequence number of the last action sent on this connection
+
+// Last full game state rebuilt from snapshots and deltas, and its sequence number
+let syncedState = null;
+let syncedSeq = 0;
+let awaitingResync = false;
+
+// Applies a gameStateDelta to syncedState. Returns false if an update was missed.
+function applyStateDelta(data) {
+    if (!syncedState || data.baseSeq !== syncedSeq) {
+        return false;
+    }
+    const delta = data.delta || {};
+    // Build a new object so consumers holding the previous state do not see it change
+    const next = { ...syncedState, map: { ...syncedState.map } };
+
+    if (delta.meta) {
+        // Meta is sent whole; optional fields that are absent were cleared
+        for (const key of ['countdown', 'elapsedTime', 'lobbyJoinEndTime', 'initialPlayerCount', 'result']) {
+            delete next[key];
+        }
+        Object.assign(next, delta.meta);
+        // A new round can bring a map of another size; tiles that differ follow in delta.tiles
+        if (next.width !== syncedState.width || next.height !== syncedState.height) {
+            next.map.blocks = Array.from({ length: next.height }, (_, y) =>
+                Array.from({ length: next.width }, (_, x) => (syncedState.map.blocks[y] || [])[x] || 0));
+        }
+    }
+
+    if (delta.tiles) {
+        next.map.blocks = next.map.blocks.map(row => row.slice());
+        for (const tile of delta.tiles) {
+            next.map.blocks[tile.y][tile.x] = tile.type;
+        }
+    }
+
+    const playersById = {};
+    for (const p of syncedState.players || []) playersById[p.id] = p;
+    for (const p of delta.players || []) playersById[p.id] = p;
+    for (const id of delta.removedPlayers || []) delete playersById[id];
+    const order = delta.playerOrder || (syncedState.players || []).map(p => p.id);
+    next.players = order.map(id => playersById[id]).filter(Boolean);
+    next.map.players = next.players;
+
+    const bombsById = {};
+    for (const b of syncedState.bombs || []) bombsById[b.ID] = b;
+    for (const b of delta.bombs || []) bombsById[b.ID] = b;
+    for (const id of delta.removedBombs || []) delete bombsById[id];
+    next.bombs = Object.values(bombsById);
+
+    next.powerUps = Object.assign({}, syncedState.powerUps, delta.powerUps);
+    for (const id of delta.removedPowerUps || []) delete next.powerUps[id];
+
+    if (delta.explosions) {
+        next.explosions = delta.explosions;
+    }
+
+    syncedState = next;
+    syncedSeq = data.seq;
+    return true;
+}
+
+// Splits a websocket frame into messages and rebuilds the full state from snapshots and
+// deltas before handing each message to onMessage
+function handleSocketMessage(event, onMessage) {
+    try {
+        const messages = event.data.split('\n');
+        for (const rawMsg of messages) {
+            if (!rawMsg.trim()) continue;
+            
+            try {
+                let data = JSON.parse(rawMsg);
+
+                // Rebuild the full state from snapshots and deltas so handlers always see a gameState
+                if (data.type === 'gameState') {
+                    syncedState = data.state;
+                    syncedSeq = data.seq;
+                    awaitingResync = false;
+                } else if (data.type === 'gameStateDelta') {
+                    if (awaitingResync) continue;
+                    if (!applyStateDelta(data)) {
+                        console.warn(`Missed game state update (have ${syncedSeq}, got base ${data.baseSeq}). Requesting resync.`);
+                        awaitingResync = true;
+                        socket.send(JSON.stringify({ type: 'resync' }));
+                        continue;
+                    }
+                    data = { type: 'gameState', seq: syncedSeq, state: syncedState };
+                }
+                // console.log("WebSocket message received:", data); // Log all messages for debugging
+                
+                if (data.type === "join_ack") {
+                    console.log("✅ Join acknowledged by server for", data.payload.playerId);
+                    hasJoined = true;
+                    // The server may have issued a new player ID; keep the session it bound us to
+                    localStorage.setItem('bomberman_currentPlayerID', data.payload.playerId);
+                    localStorage.setItem('bomberman_sessionToken', data.payload.sessionToken);
+                    // Continue to call onMessage for join_ack if your main handler needs it,
+                    // otherwise, you could 'continue;' here.
+                }
+                
+                onMessage(data); // Pass all parsed messages to the main handler
+            } catch (e) {
+                console.error("Failed to parse individual WS message:", rawMsg.substring(0, 100) + "...", e);
+            }
+        }
+    } catch (error) {
+        console.error("Error in onmessage handler:", error);
+    }
+}
+
+// The room is taken from the page URL (?room=<id>); empty means the server's default room
+function currentRoomId() {
+    return new URLSearchParams(window.location.search).get('room') || '';
+}
+
+// A recorded match is watched with ?replay=<id>
+function currentReplayId() {
+    return new URLSearchParams(window.location.search).get('replay') || '';
+}
 
 function connectWebSocket(nickname, playerId, onMessage) {
     // If an old socket exists and is open or connecting, close it and clear handlers
@@ -14,9 +133,14 @@ function connectWebSocket(nickname, playerId, onMessage) {
     }
     socket = null; // Ensure the old socket reference is cleared
     hasJoined = false; // Reset join status
+    inputSeq = 0; // The server restarts action numbering when we rejoin
+    syncedState = null; // The new connection starts from a fresh snapshot
+    syncedSeq = 0;
+    awaitingResync = false;
 
     console.log(`Attempting to connect WebSocket for ${nickname} (${playerId})`);
-    socket = new WebSocket('ws://localhost:8080/ws');
+    const roomId = currentRoomId();
+    socket = new WebSocket('ws://localhost:8080/ws' + (roomId ? `?room=${encodeURIComponent(roomId)}` : ''));
 
     socket.onopen = () => {
         console.log("WebSocket connection opened.");
@@ -24,38 +148,13 @@ function connectWebSocket(nickname, playerId, onMessage) {
         const msg = {
             type: 'join',
             playerId: playerId,
-            payload: { nickname }
+            payload: { nickname, token: localStorage.getItem('bomberman_sessionToken') || '' }
         };
-        console.log("Sending join message:", msg);
+        console.log("Sending join message for", playerId);
         socket.send(JSON.stringify(msg));
     };
 
-    socket.onmessage = (event) => {
-        try {
-            const messages = event.data.split('\n');
-            for (const rawMsg of messages) {
-                if (!rawMsg.trim()) continue;
-                
-                try {
-                    const data = JSON.parse(rawMsg);
-                    // console.log("WebSocket message received:", data); // Log all messages for debugging
-                    
-                    if (data.type === "join_ack") {
-                        console.log("✅ Join acknowledged by server:", data.payload);
-                        hasJoined = true;
-                        // Continue to call onMessage for join_ack if your main handler needs it,
-                        // otherwise, you could 'continue;' here.
-                    }
-                    
-                    onMessage(data); // Pass all parsed messages to the main handler
-                } catch (e) {
-                    console.error("Failed to parse individual WS message:", rawMsg.substring(0, 100) + "...", e);
-                }
-            }
-        } catch (error) {
-            console.error("Error in onmessage handler:", error);
-        }
-    };
+    socket.onmessage = (event) => handleSocketMessage(event, onMessage);
 
     socket.onerror = (error) => {
         console.error("WebSocket error:", error);
@@ -71,10 +170,44 @@ function connectWebSocket(nickname, playerId, onMessage) {
     };
 }
 
+// Connects to the playback of a recorded match. The server streams it like a live game
+// but does not accept join or actions, only replay_control.
+function connectReplay(replayId, onMessage) {
+    if (socket) {
+        socket.onclose = null;
+        socket.close();
+    }
+    syncedState = null;
+    syncedSeq = 0;
+    awaitingResync = false;
+
+    socket = new WebSocket(`ws://localhost:8080/ws/replay?id=${encodeURIComponent(replayId)}`);
+    socket.onmessage = (event) => handleSocketMessage(event, onMessage);
+    socket.onerror = (error) => {
+        console.error("Replay WebSocket error:", error);
+    };
+    socket.onclose = (event) => {
+        console.log("Replay connection closed.", event.code, event.reason);
+        socket = null;
+    };
+}
+
+// Steers a replay: { speed }, { paused } and/or { seekMs }
+function sendReplayControl(control) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: 'replay_control', payload: control }));
+    }
+}
+
+function nextInputSeq() {
+    inputSeq += 1;
+    return inputSeq;
+}
+
 function isJoined() {
     return hasJoined && socket && socket.readyState === WebSocket.OPEN;
 }
 
 // Export socket if it needs to be accessed directly for specific scenarios (e.g., sending messages),
 // but generally, interactions should be through exported functions.
-export { connectWebSocket, socket, isJoined };
+export { connectWebSocket, connectReplay, sendReplayControl, socket, isJoined, currentRoomId, currentReplayId, nextInputSeq };